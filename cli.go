@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --- Command Line ---
+
+const usageText = `Usage: sms-tracker [command] [flags]
+
+Commands:
+  serve          Run the tracker and its web interface (default)
+  validate-data  Check the game data files for errors and inconsistencies
+  dump-ram       Write Dolphin's emulated main memory to a file
+  help           Show this help
+
+Run "sms-tracker <command> -h" for the flags of a command.
+`
+
+// ServeOptions are the command line flags of "serve". Zero values keep what config.json says.
+type ServeOptions struct {
+	ConfigPath string
+	DataDir    string
+	GameData   string
+	Port       int
+	Bind       string
+	NoHook     bool
+	Record     string
+	Replay     string
+	Speed      float64
+}
+
+// applyTo overrides config file values with the ones given on the command line.
+func (o ServeOptions) applyTo(cfg *Config) {
+	if o.Port != 0 {
+		cfg.Port = o.Port
+	}
+	if o.GameData != "" {
+		cfg.GameDataDir = o.GameData
+	}
+}
+
+// runCommand dispatches to the subcommand in args and returns the exit code.
+// Without a command (or when args start with a flag) the tracker is served, so double-clicking the binary still works.
+func runCommand(args []string) int {
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "validate-data":
+		return runValidateData(args)
+	case "dump-ram":
+		return runDumpRAM(args)
+	case "help":
+		fmt.Print(usageText)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usageText)
+		return 2
+	}
+}
+
+func runServe(args []string) int {
+	var opts ServeOptions
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&opts.ConfigPath, "config", "", "path of the config file (default <data-dir>/config.json)")
+	fs.StringVar(&opts.DataDir, "data-dir", "", "directory for saves, history, runs and the default config (default: working directory)")
+	fs.StringVar(&opts.GameData, "game-data", "", "directory with zones.json, unlocks.json and blue_coin.json overrides (overrides gameDataDir)")
+	fs.IntVar(&opts.Port, "port", 0, "port of the web interface, overrides the config file")
+	fs.StringVar(&opts.Bind, "bind", "", "address to listen on, e.g. 127.0.0.1, 0.0.0.0 or :: (overrides hostInNetwork)")
+	fs.BoolVar(&opts.NoHook, "no-hook", false, "don't hook into Dolphin, only track manually")
+	fs.StringVar(&opts.Record, "record", "", "record every memory read of this session to the given file")
+	fs.StringVar(&opts.Replay, "replay", "", "play a recorded session back instead of hooking into Dolphin")
+	fs.Float64Var(&opts.Speed, "speed", 1, "playback speed factor for --replay")
+	fs.Parse(args)
+
+	if opts.Port < 0 || opts.Port > 65535 {
+		fmt.Fprintf(os.Stderr, "Invalid port %d\n", opts.Port)
+		return 2
+	}
+	if opts.NoHook && (opts.Record != "" || opts.Replay != "") {
+		fmt.Fprintln(os.Stderr, "--no-hook can't be combined with --record or --replay")
+		return 2
+	}
+
+	serve(opts)
+	return 0
+}
+
+func runValidateData(args []string) int {
+	fs := flag.NewFlagSet("validate-data", flag.ExitOnError)
+	overlayDir := fs.String("game-data", "", "also apply the overrides in this directory")
+	strict := fs.Bool("strict", true, "fail on errors like strictData does, -strict=false only reports them")
+	fs.Parse(args)
+
+	world, err := parseGameData(overlayGameData(*overlayDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Game data is invalid: %v\n", err)
+		return 1
+	}
+	problems := validateWorld(&world)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if err := checkStrict(problems); err != nil {
+		fmt.Fprintf(os.Stderr, "Game data is invalid: %v\n", err)
+		if *strict {
+			return 1
+		}
+		return 0
+	}
+	_, warnings := countDataProblems(problems)
+	fmt.Printf("Game data OK: %d zones, %d entrances, %d unlocks, %d blue coins, %d warnings.\n",
+		len(world.Zones), len(world.PlazaEntrances), len(world.Unlocks), len(world.BlueCoins), warnings)
+	return 0
+}
+
+func runDumpRAM(args []string) int {
+	fs := flag.NewFlagSet("dump-ram", flag.ExitOnError)
+	out := fs.String("o", "ram.bin", "file to write the RAM dump to")
+	fs.Parse(args)
+
+	if err := dumpRAM(&dolphinProcess{}, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error dumping RAM: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %d bytes of main memory to %s. Use it with \"memoryDump\" in config.json.\n", GC_RAM_SIZE, *out)
+	return 0
+}
+
+// dumpRAM copies the whole main memory of source into a file LoadMemoryDump can read.
+func dumpRAM(source MemorySource, path string) error {
+	if err := source.Open(); err != nil {
+		return fmt.Errorf("hooking into Dolphin: %w", err)
+	}
+	defer source.Close()
+
+	const blockSize = 0x400000
+	ram := make([]byte, 0, GC_RAM_SIZE)
+	for offset := 0; offset < GC_RAM_SIZE; offset += blockSize {
+		block, err := source.Read(GC_RAM_BASE+uint32(offset), blockSize)
+		if err != nil {
+			return fmt.Errorf("reading 0x%08X: %w", GC_RAM_BASE+uint32(offset), err)
+		}
+		ram = append(ram, block...)
+	}
+	return writeFileAtomic(path, ram, 0)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// --- Config Validation & Reload ---
+
+const CONFIG_POLL_INTERVAL = 2 * time.Second
+
+// liveConfig is the config in effect. It is replaced as a whole when config.json changes,
+// so readers always see a consistent config and must not modify it.
+var liveConfig atomic.Pointer[Config]
+
+func init() {
+	cfg := defaultConfig
+	liveConfig.Store(&cfg)
+}
+
+// currentConfig returns the config in effect. It is never nil.
+func currentConfig() *Config {
+	return liveConfig.Load()
+}
+
+func setConfig(cfg Config) {
+	liveConfig.Store(&cfg)
+}
+
+// ConfigProblem is something wrong in a config file. Warnings don't prevent the config from being used.
+type ConfigProblem struct {
+	Line    int // 0 if unknown
+	Key     string
+	Message string
+	Warning bool
+}
+
+func (p ConfigProblem) String() string {
+	var b strings.Builder
+	if p.Warning {
+		b.WriteString("warning: ")
+	} else {
+		b.WriteString("error: ")
+	}
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Key != "" {
+		fmt.Fprintf(&b, "%s: ", p.Key)
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+func hasErrors(problems []ConfigProblem) bool {
+	for _, p := range problems {
+		if !p.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfig reads a config file on top of base and reports everything that is wrong with it.
+func parseConfig(data []byte, base Config) (Config, []ConfigProblem) {
+	cfg := base
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return base, []ConfigProblem{{Line: lineAt(data, syntaxErr.Offset), Message: syntaxErr.Error()}}
+		case errors.As(err, &typeErr):
+			return base, []ConfigProblem{{Line: lineAt(data, typeErr.Offset), Key: typeErr.Field,
+				Message: fmt.Sprintf("expected a %s, got a %s", typeErr.Type, typeErr.Value)}}
+		default:
+			return base, []ConfigProblem{{Message: err.Error()}}
+		}
+	}
+
+	// The file is valid JSON at this point, so walking it can't fail
+	lines := make(map[string]int)
+	var problems []ConfigProblem
+	checkKeys(json.NewDecoder(bytes.NewReader(data)), data, reflect.TypeOf(cfg), "", lines, &problems)
+
+	for _, p := range cfg.validate() {
+		p.Line = lines[p.Key]
+		problems = append(problems, p)
+	}
+	return cfg, problems
+}
+
+// autoMappingMode returns the configured autoMapping mode, "propose" if none is set.
+func (c *Config) autoMappingMode() string {
+	if c.AutoMapping == "" {
+		return AUTO_MAPPING_PROPOSE
+	}
+	return c.AutoMapping
+}
+
+// validate checks the values of a config.
+func (c Config) validate() []ConfigProblem {
+	var problems []ConfigProblem
+	if c.Port < 1 || c.Port > 65535 {
+		problems = append(problems, ConfigProblem{Key: "port", Message: fmt.Sprintf("%d is not a valid port (1-65535)", c.Port)})
+	}
+	if c.TrackerIntervalSeconds < 1 {
+		problems = append(problems, ConfigProblem{Key: "trackerIntervalSeconds", Message: "must be at least 1"})
+	}
+
+	if c.DolphinGameID != "" && (len(c.DolphinGameID) != GAME_ID_SIZE || parseGameID([]byte(strings.ToUpper(c.DolphinGameID))) == "") {
+		problems = append(problems, ConfigProblem{Key: "dolphinGameId", Message: fmt.Sprintf("%q is not a game ID like GMSE01", c.DolphinGameID)})
+	}
+	if _, err := newProcessMatcher(c.DolphinProcessNames, nil); err != nil {
+		problems = append(problems, ConfigProblem{Key: "dolphinProcessNames", Message: err.Error()})
+	}
+	if _, err := newProcessMatcher(nil, c.DolphinCmdlinePatterns); err != nil {
+		problems = append(problems, ConfigProblem{Key: "dolphinCmdlinePatterns", Message: err.Error()})
+	}
+	switch c.AutoMapping {
+	case "", AUTO_MAPPING_PROPOSE, AUTO_MAPPING_RECORD, AUTO_MAPPING_OFF:
+	default:
+		problems = append(problems, ConfigProblem{Key: "autoMapping", Message: fmt.Sprintf("unknown mode %q (propose, record or off)", c.AutoMapping)})
+	}
+
+	checkTrigger := func(key string, t TimerTrigger) {
+		switch t.Type {
+		case "", TRIGGER_LEVEL, TRIGGER_ZONE, TRIGGER_SKILL, TRIGGER_SHINES:
+		default:
+			problems = append(problems, ConfigProblem{Key: key + ".type", Message: fmt.Sprintf("unknown trigger type %q", t.Type)})
+		}
+	}
+	checkTrigger("timer.start", c.Timer.Start)
+	checkTrigger("timer.finish", c.Timer.Finish)
+	for i, t := range c.Timer.Splits {
+		checkTrigger(fmt.Sprintf("timer.splits[%d]", i), t)
+	}
+	for i, t := range c.LiveSplit.Triggers {
+		key := fmt.Sprintf("livesplit.triggers[%d]", i)
+		checkTrigger(key, t.TimerTrigger)
+		if !livesplitCommands[t.Command] {
+			problems = append(problems, ConfigProblem{Key: key + ".command", Message: fmt.Sprintf("unknown command %q", t.Command)})
+		}
+	}
+	return problems
+}
+
+// checkKeys walks the next JSON value and reports object keys that don't exist in t.
+// It records the line of every key it sees under its path (e.g. "timer.splits[0].type").
+func checkKeys(dec *json.Decoder, data []byte, t reflect.Type, path string, lines map[string]int, problems *[]ConfigProblem) {
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch delim {
+	case '[':
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := 0; dec.More(); i++ {
+			checkKeys(dec, data, elem, fmt.Sprintf("%s[%d]", path, i), lines, problems)
+		}
+	case '{':
+		var fields map[string]reflect.Type
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = jsonFields(t)
+		}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return
+			}
+			key := keyTok.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			line := lineAt(data, dec.InputOffset())
+			lines[keyPath] = line
+
+			fieldType, known := lookupField(fields, key)
+			if fields != nil && !known {
+				*problems = append(*problems, ConfigProblem{Line: line, Key: keyPath, Message: "unknown key", Warning: true})
+			}
+			checkKeys(dec, data, fieldType, keyPath, lines, problems)
+		}
+	}
+	dec.Token() // Closing delimiter
+}
+
+// jsonFields returns the JSON names of the fields of a struct type, including those of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for n, ft := range jsonFields(f.Type) {
+				fields[n] = ft
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField finds a key the way encoding/json does: exact match first, then case-insensitive.
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if ft, ok := fields[key]; ok {
+		return ft, true
+	}
+	for name, ft := range fields {
+		if strings.EqualFold(name, key) {
+			return ft, true
+		}
+	}
+	return nil, false
+}
+
+// lineAt returns the 1-based line of a byte offset.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// watchConfig polls the config file and applies the settings that can change while running:
+// the tracker interval, the auto-track default, the spoiler switch, the auto-mapping mode and the Dolphin instance pin. An invalid file keeps the old config.
+func watchConfig(path string, opts ServeOptions) {
+	lastMod := time.Time{}
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	for {
+		time.Sleep(CONFIG_POLL_INTERVAL)
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		cfg, problems := parseConfig(data, defaultConfig)
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", path, p)
+		}
+		if hasErrors(problems) {
+			fmt.Printf("Keeping the previous config until %s is fixed.\n", path)
+			continue
+		}
+		opts.applyTo(&cfg)
+		applyConfigChange(cfg)
+	}
+}
+
+// applyConfigChange takes over the live settings of cfg and tells about the ones that need a restart.
+func applyConfigChange(cfg Config) {
+	old := currentConfig()
+	next := *old
+	next.TrackerIntervalSeconds = cfg.TrackerIntervalSeconds
+	next.AutoTrackDefault = cfg.AutoTrackDefault
+	next.SpoilerEnabled = cfg.SpoilerEnabled
+	next.AutoMapping = cfg.AutoMapping
+	next.DolphinPID, next.DolphinGameID = cfg.DolphinPID, cfg.DolphinGameID
+	next.DolphinProcessNames, next.DolphinCmdlinePatterns = cfg.DolphinProcessNames, cfg.DolphinCmdlinePatterns
+
+	restart := cfg
+	restart.TrackerIntervalSeconds, restart.AutoTrackDefault, restart.SpoilerEnabled = next.TrackerIntervalSeconds, next.AutoTrackDefault, next.SpoilerEnabled
+	restart.AutoMapping = next.AutoMapping
+	restart.DolphinPID, restart.DolphinGameID = next.DolphinPID, next.DolphinGameID
+	restart.DolphinProcessNames, restart.DolphinCmdlinePatterns = next.DolphinProcessNames, next.DolphinCmdlinePatterns
+	if !reflect.DeepEqual(restart, next) {
+		fmt.Println("Config changed: port, network, memory dump, timer and LiveSplit settings take effect after a restart.")
+	}
+	if reflect.DeepEqual(next, *old) {
+		return
+	}
+
+	setConfig(next)
+	if next.DolphinPID != old.DolphinPID || next.DolphinGameID != old.DolphinGameID {
+		// Replaces a pin set through /api/instances as well
+		setPin(InstancePin{PID: next.DolphinPID, GameID: next.DolphinGameID})
+		fmt.Printf("Pinned Dolphin instance: %s\n", currentPin())
+	}
+	if !reflect.DeepEqual(next.DolphinProcessNames, old.DolphinProcessNames) || !reflect.DeepEqual(next.DolphinCmdlinePatterns, old.DolphinCmdlinePatterns) {
+		// validate already compiled the patterns
+		matcher, _ := newProcessMatcher(next.DolphinProcessNames, next.DolphinCmdlinePatterns)
+		setMatcher(matcher)
+	}
+	fmt.Printf("Config reloaded: interval %ds, auto-track %t, spoilers %t, auto-mapping %s.\n",
+		next.TrackerIntervalSeconds, next.AutoTrackDefault, next.SpoilerEnabled, next.autoMappingMode())
+	trackerEvents.Publish(TrackerEvent{Type: EVENT_CONFIG_CHANGED})
+}
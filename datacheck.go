@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- Game Data Validation ---
+
+// DataProblem is an inconsistency in the game data files. Path is the key path inside File.
+type DataProblem struct {
+	File    string `json:"file"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning"`
+}
+
+func (p DataProblem) String() string {
+	level := "error"
+	if p.Warning {
+		level = "warning"
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", level, p.File, p.Path, p.Message)
+}
+
+// countDataProblems returns the number of errors and warnings.
+func countDataProblems(problems []DataProblem) (errors, warnings int) {
+	for _, p := range problems {
+		if p.Warning {
+			warnings++
+		} else {
+			errors++
+		}
+	}
+	return errors, warnings
+}
+
+// checkStrict is the rule of strict mode, shared by strictData (at startup and on /api/data/reload) and validate-data:
+// errors reject the game data, warnings are only reported.
+func checkStrict(problems []DataProblem) error {
+	if errCount, warnings := countDataProblems(problems); errCount > 0 {
+		return fmt.Errorf("game data has %d errors and %d warnings", errCount, warnings)
+	}
+	return nil
+}
+
+// validateWorld cross-checks zones.json, blue_coin.json, unlocks.json and stages.json against each other and against skillNames.
+func validateWorld(w *WorldData) []DataProblem {
+	var problems []DataProblem
+	report := func(file, path string, warning bool, format string, args ...any) {
+		problems = append(problems, DataProblem{File: file, Path: path, Message: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	// Blue coins
+	blueCoins := make(map[string]bool)
+	for i, bc := range w.BlueCoins {
+		path := fmt.Sprintf("[%d].id", i)
+		if blueCoins[bc.ID] {
+			report("blue_coin.json", path, false, "duplicate blue coin ID %q", bc.ID)
+		}
+		blueCoins[bc.ID] = true
+		if _, ok := parseBlueCoinFlag(bc.ID); !ok {
+			report("blue_coin.json", path, true, "ID %q doesn't encode a game flag, it can't be auto-tracked", bc.ID)
+		}
+	}
+
+	// Zones, in a stable order
+	zoneIDs := make([]string, 0, len(w.Zones))
+	for id := range w.Zones {
+		zoneIDs = append(zoneIDs, id)
+	}
+	sort.Strings(zoneIDs)
+
+	type shineUse struct {
+		path  string
+		numID int
+	}
+	shineIDs := make(map[string]shineUse) // Shine ID -> first place it was defined
+	numIDs := make(map[int]string)        // num_id -> shine ID
+	referencedCoins := make(map[string]bool)
+
+	for _, zoneID := range zoneIDs {
+		zone := w.Zones[zoneID]
+		prefix := "zones." + zoneID
+
+		for i, shine := range zone.ShinesAvailable {
+			path := fmt.Sprintf("%s.shines_available[%d]", prefix, i)
+			if first, ok := shineIDs[shine.ID]; ok {
+				// Secret shines are listed in every episode of their zone, that's fine as long as they agree
+				if first.numID != shine.NumID {
+					report("zones.json", path+".num_id", false, "shine %q has num_id %d here but %d at %s", shine.ID, shine.NumID, first.numID, first.path)
+				}
+			} else {
+				shineIDs[shine.ID] = shineUse{path: path, numID: shine.NumID}
+			}
+
+			if shine.NumID == NO_SHINE_ID {
+				continue
+			}
+			if shine.NumID < 0 || shine.NumID >= SHINE_FLAG_COUNT {
+				report("zones.json", path+".num_id", false, "num_id %d is outside of the shine flags (0-%d, or %d for unmapped)", shine.NumID, SHINE_FLAG_COUNT-1, NO_SHINE_ID)
+				continue
+			}
+			if other, ok := numIDs[shine.NumID]; ok && other != shine.ID {
+				report("zones.json", path+".num_id", false, "num_id %d is shared by %q and %q", shine.NumID, other, shine.ID)
+			} else if !ok {
+				numIDs[shine.NumID] = shine.ID
+			}
+		}
+
+		for i, coinID := range zone.BlueCoinIDs {
+			referencedCoins[coinID] = true
+			if !blueCoins[coinID] {
+				report("zones.json", fmt.Sprintf("%s.blue_coin_ids[%d]", prefix, i), false, "unknown blue coin %q", coinID)
+			}
+		}
+	}
+
+	for i, bc := range w.BlueCoins {
+		if !referencedCoins[bc.ID] {
+			report("blue_coin.json", fmt.Sprintf("[%d].id", i), true, "blue coin %q isn't listed in any zone", bc.ID)
+		}
+	}
+
+	// Unlocks are the lower case skill names the memory scanner reports
+	unlocks := make(map[string]bool)
+	for i, u := range w.Unlocks {
+		path := fmt.Sprintf("unlocks[%d].id", i)
+		if unlocks[u.ID] {
+			report("unlocks.json", path, false, "duplicate unlock ID %q", u.ID)
+		}
+		unlocks[u.ID] = true
+		if !containsString(skillNames, strings.ToUpper(u.ID)) {
+			report("unlocks.json", path, false, "unlock %q doesn't match any skill the tracker reads from memory", u.ID)
+		}
+	}
+	for _, skill := range skillNames {
+		if !unlocks[strings.ToLower(skill)] {
+			report("unlocks.json", "unlocks", true, "no unlock for skill %s", skill)
+		}
+	}
+
+	// Stages must point to zones that exist
+	stageIDs := make(map[string]bool)
+	stageIndexes := make(map[int]bool)
+	for i, stage := range w.Stages {
+		path := fmt.Sprintf("stages[%d]", i)
+		if stageIDs[stage.ID] {
+			report("stages.json", path+".id", false, "duplicate stage ID %q", stage.ID)
+		}
+		if stageIndexes[stage.Index] {
+			report("stages.json", path+".index", false, "duplicate stage index %d", stage.Index)
+		}
+		stageIDs[stage.ID], stageIndexes[stage.Index] = true, true
+
+		episodes := make([]string, 0, len(stage.Zones))
+		for episode := range stage.Zones {
+			episodes = append(episodes, episode)
+		}
+		sort.Strings(episodes)
+		for _, episode := range episodes {
+			if _, ok := w.Zones[stage.Zones[episode]]; !ok {
+				report("stages.json", fmt.Sprintf("%s.zones.%s", path, episode), false, "unknown zone %q", stage.Zones[episode])
+			}
+		}
+		if _, ok := w.Zones[stage.Default]; stage.Default != "" && !ok {
+			report("stages.json", path+".default", false, "unknown zone %q", stage.Default)
+		}
+		if stage.Level != "" && !slices.Contains(levels, stage.Level) {
+			report("stages.json", path+".level", false, "unknown level %q, SyncLocation never finds it", stage.Level)
+		}
+		if len(stage.Zones) == 0 && stage.Default == "" && !hasZonePrefix(zoneIDs, stage.ID) {
+			report("stages.json", path+".id", true, "no zone starts with %q, stage %d never resolves to a zone", stage.ID, stage.Index)
+		}
+	}
+
+	// Zones no stage index leads to are never reported as the current zone
+	var unreachable []string
+	for _, zoneID := range zoneIDs {
+		if !w.zoneReachable(zoneID) {
+			unreachable = append(unreachable, zoneID)
+		}
+	}
+	if len(unreachable) > 0 {
+		report("stages.json", "stages", true, "no stage resolves to %d zones of zones.json, they are never detected as the current zone: %s",
+			len(unreachable), strings.Join(unreachable, ", "))
+	}
+
+	// Every region needs all addresses inside main memory
+	regionIDs := make(map[string]bool)
+	for i, region := range w.Regions {
+		path := fmt.Sprintf("regions[%d]", i)
+		if len(region.ID) != GAME_ID_SIZE || parseGameID([]byte(region.ID)) == "" {
+			report("regions.json", path+".id", false, "%q is not a game ID like GMSE01", region.ID)
+		}
+		if regionIDs[region.ID] {
+			report("regions.json", path+".id", false, "duplicate region %q", region.ID)
+		}
+		regionIDs[region.ID] = true
+
+		for _, name := range region.Addresses.invalid() {
+			report("regions.json", path+".addresses."+name, false, "address is missing or outside of main memory, %s isn't read", region.ID)
+		}
+	}
+	if len(w.Regions) == 0 {
+		report("regions.json", "regions", false, "no regions, the tracker can't read any game")
+	}
+
+	return problems
+}
+
+// hasZonePrefix reports whether one of the sorted zone IDs starts with prefix.
+func hasZonePrefix(zoneIDs []string, prefix string) bool {
+	i := sort.SearchStrings(zoneIDs, prefix)
+	return i < len(zoneIDs) && strings.HasPrefix(zoneIDs[i], prefix)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEmbeddedGameDataPassesStrictMode(t *testing.T) {
+	world := loadTestWorld(t)
+	problems := validateWorld(world)
+	for _, p := range problems {
+		if !p.Warning {
+			t.Error(p)
+		}
+	}
+	if err := checkStrict(problems); err != nil {
+		t.Errorf("strict mode rejects the built-in game data: %v", err)
+	}
+}
+
+func TestCheckStrict(t *testing.T) {
+	warning := DataProblem{File: "blue_coin.json", Path: "[0].id", Message: "not listed", Warning: true}
+	failure := DataProblem{File: "zones.json", Path: "zones.bianco0.blue_coin_ids[0]", Message: "unknown blue coin"}
+	tests := []struct {
+		name     string
+		problems []DataProblem
+		rejected bool
+	}{
+		{"no problems", nil, false},
+		{"only warnings", []DataProblem{warning, warning}, false},
+		{"an error", []DataProblem{warning, failure}, true},
+	}
+	for _, tt := range tests {
+		if err := checkStrict(tt.problems); (err != nil) != tt.rejected {
+			t.Errorf("%s: checkStrict() = %v, want rejected %v", tt.name, err, tt.rejected)
+		}
+	}
+}
+
+func TestValidateWorldFindsProblems(t *testing.T) {
+	tests := []struct {
+		fixture string // Overrides in testdata/gamedata
+		want    DataProblem
+	}{
+		{"unknown_blue_coin", DataProblem{File: "zones.json", Path: "zones.bianco0.blue_coin_ids[0]", Message: `unknown blue coin "not_a_coin"`}},
+		{"duplicate_num_id", DataProblem{File: "zones.json", Path: "zones.bianco0.shines_available[1].num_id", Message: `num_id 86 is shared by "airport1_1" and "bianco0_copy"`}},
+		{"unknown_unlock", DataProblem{File: "unlocks.json", Path: "unlocks[23].id", Message: `unlock "jetpack" doesn't match any skill the tracker reads from memory`}},
+		{"missing_stage_zone", DataProblem{File: "stages.json", Path: "stages[2].default", Message: `unknown zone "bianco9"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			world, err := parseGameData(overlayGameData("testdata/gamedata/" + tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			problems := validateWorld(&world)
+			if !slices.Contains(problems, tt.want) {
+				t.Errorf("problems %v\ndon't include %v", problems, tt.want)
+			}
+			if checkStrict(problems) == nil {
+				t.Error("strict mode accepts the broken game data")
+			}
+		})
+	}
+}
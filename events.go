@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Tracker Events ---
+
+// Event types pushed to /api/events
+const (
+	EVENT_HOOK_CONNECTED      = "hook_connected"
+	EVENT_HOOK_LOST           = "hook_lost"
+	EVENT_LEVEL_CHANGED       = "level_changed"
+	EVENT_EPISODE_CHANGED     = "episode_changed"
+	EVENT_ZONE_CHANGED        = "zone_changed" // The resolved zone from zones.json changed
+	EVENT_SKILL_UNLOCKED      = "skill_unlocked"
+	EVENT_SKILL_LOST          = "skill_lost"
+	EVENT_SEED_CHANGED        = "seed_changed"
+	EVENT_SHINE_COLLECTED     = "shine_collected"
+	EVENT_BLUE_COIN_COLLECTED = "blue_coin_collected"
+	EVENT_SHINE_TOTAL_CHANGED = "shine_total_changed"
+	EVENT_STATE_CHANGED       = "state_changed"    // The persisted tracker state got a new revision
+	EVENT_SLOT_CHANGED        = "slot_changed"     // A different save slot became active
+	EVENT_CONFIG_CHANGED      = "config_changed"   // config.json was reloaded with new live settings
+	EVENT_DATA_RELOADED       = "data_reloaded"    // The game data files were reloaded with changes
+	EVENT_MAPPING_PROPOSED    = "mapping_proposed" // An entrance or exit mapping was observed, see /api/mappings
+)
+
+const (
+	eventBacklogSize    = 512 // Events kept around so reconnecting clients can catch up
+	eventSubscriberSize = 64  // Buffered events per subscriber before it gets dropped
+	// Pass as afterID to Subscribe to skip the backlog and only receive new events
+	eventsNewOnly = ^uint64(0)
+)
+
+// TrackerEvent is a single change detected by the scanner.
+type TrackerEvent struct {
+	ID       uint64    `json:"id"` // Monotonically increasing, used as the SSE event ID
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level,omitempty"`
+	Episode  string    `json:"episode,omitempty"`
+	Zone     string    `json:"zone,omitempty"` // Zone ID from zones.json
+	Skill    string    `json:"skill,omitempty"`
+	Shine    string    `json:"shine,omitempty"`     // Shine ID from zones.json
+	BlueCoin string    `json:"blue_coin,omitempty"` // Blue coin ID from blue_coin.json
+	Seed     string    `json:"seed,omitempty"`
+	Previous string    `json:"previous,omitempty"` // Value before the change (level, episode, zone or seed)
+	Revision uint64    `json:"revision,omitempty"` // Tracker state revision for state_changed
+	// Shine total at the time of a skill change or shine_total_changed
+	ShineTotal int `json:"shine_total,omitempty"`
+	// Numeric ID of the shine linked to the skill (see the shines address of regions.json) for skill changes
+	LinkedShineID *uint32 `json:"linked_shine_id,omitempty"`
+}
+
+// EventHub fans out tracker events to all subscribers and keeps a short backlog for resuming.
+type EventHub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	backlog     []TrackerEvent
+	subscribers map[chan TrackerEvent]struct{}
+}
+
+var trackerEvents = NewEventHub()
+
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[chan TrackerEvent]struct{})}
+}
+
+// Publish assigns the next ID to the event and delivers it to every subscriber.
+// Subscribers that can't keep up are dropped; they can resume with their last seen ID.
+func (h *EventHub) Publish(ev TrackerEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	ev.ID = h.lastID
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	h.backlog = append(h.backlog, ev)
+	if len(h.backlog) > eventBacklogSize {
+		h.backlog = h.backlog[len(h.backlog)-eventBacklogSize:]
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns all backlog events newer than afterID and a channel for upcoming events.
+// The returned cancel function must be called once the subscriber is done.
+func (h *EventHub) Subscribe(afterID uint64) ([]TrackerEvent, <-chan TrackerEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// An ID we never handed out comes from before a restart of the tracker, so the whole backlog is new to the client
+	if afterID != eventsNewOnly && afterID > h.lastID {
+		afterID = 0
+	}
+	var missed []TrackerEvent
+	for _, ev := range h.backlog {
+		if afterID != eventsNewOnly && ev.ID > afterID {
+			missed = append(missed, ev)
+		}
+	}
+
+	ch := make(chan TrackerEvent, eventSubscriberSize)
+	h.subscribers[ch] = struct{}{}
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+	return missed, ch, cancel
+}
+
+// Listen calls fn for every new event on a separate goroutine.
+// Should the listener fall behind, it resubscribes and catches up from the backlog.
+func (h *EventHub) Listen(fn func(TrackerEvent)) {
+	missed, ch, cancel := h.Subscribe(eventsNewOnly)
+	go func() {
+		lastID := eventsNewOnly
+		for {
+			for _, ev := range missed {
+				fn(ev)
+				lastID = ev.ID
+			}
+			for ev := range ch {
+				fn(ev)
+				lastID = ev.ID
+			}
+			cancel()
+			missed, ch, cancel = h.Subscribe(lastID)
+		}
+	}()
+}
+
+// publishChanges compares two consecutive snapshots and publishes an event for every difference.
+func (h *EventHub) publishChanges(prev, next *ScannerSnapshot) {
+	if prev == nil {
+		prev = &ScannerSnapshot{}
+	}
+
+	if prev.IsHooked && !next.IsHooked {
+		h.Publish(TrackerEvent{Type: EVENT_HOOK_LOST})
+		return
+	}
+	if !next.IsHooked {
+		return
+	}
+
+	// The seed goes first, so listeners file everything after it under the right seed
+	if prev.Seed != next.Seed && next.Seed != "" {
+		h.Publish(TrackerEvent{Type: EVENT_SEED_CHANGED, Seed: next.Seed, Previous: prev.Seed})
+	}
+	if !prev.IsHooked {
+		h.Publish(TrackerEvent{Type: EVENT_HOOK_CONNECTED})
+	}
+	if prev.CurrentLevel != next.CurrentLevel {
+		h.Publish(TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: next.CurrentLevel, Previous: prev.CurrentLevel})
+	}
+	if prev.CurrentEpisode != next.CurrentEpisode {
+		h.Publish(TrackerEvent{Type: EVENT_EPISODE_CHANGED, Level: next.CurrentLevel, Episode: next.CurrentEpisode, Previous: prev.CurrentEpisode})
+	}
+	if prev.CurrentZoneID != next.CurrentZoneID {
+		h.Publish(TrackerEvent{Type: EVENT_ZONE_CHANGED, Level: next.CurrentLevel, Zone: next.CurrentZoneID, Previous: prev.CurrentZoneID})
+	}
+	// Skill bytes are only comparable once both snapshots actually read them
+	if len(prev.Skills) > 0 && len(next.Skills) > 0 {
+		for i, name := range skillNames {
+			had, has := prev.HasSkill(i), next.HasSkill(i)
+			if had == has {
+				continue
+			}
+			evType := EVENT_SKILL_UNLOCKED
+			if had {
+				evType = EVENT_SKILL_LOST
+			}
+			ev := TrackerEvent{Type: evType, Skill: name, Level: next.CurrentLevel, Episode: next.CurrentEpisode, ShineTotal: next.TotalShines}
+			if i < len(next.ShineIDs) {
+				linked := next.ShineIDs[i]
+				ev.LinkedShineID = &linked
+			}
+			h.Publish(ev)
+		}
+	}
+	// Same for the flags, otherwise every shine would be "collected" right after hooking
+	if len(prev.Flags) > 0 && len(next.Flags) > 0 {
+		world := currentWorld()
+		for numID := 0; numID < SHINE_FLAG_COUNT; numID++ {
+			if flagSet(prev.Flags, numID) || !flagSet(next.Flags, numID) {
+				continue
+			}
+			for _, id := range world.shinesByNumID[numID] {
+				h.Publish(TrackerEvent{Type: EVENT_SHINE_COLLECTED, Shine: id, Level: next.CurrentLevel, Episode: next.CurrentEpisode})
+			}
+		}
+		for id, flag := range world.blueCoinFlags {
+			if !flagSet(prev.Flags, flag) && flagSet(next.Flags, flag) {
+				h.Publish(TrackerEvent{Type: EVENT_BLUE_COIN_COLLECTED, BlueCoin: id, Level: next.CurrentLevel, Episode: next.CurrentEpisode})
+			}
+		}
+	}
+	// A fresh hook has nothing to compare the total against
+	if prev.IsHooked && prev.TotalShines != next.TotalShines {
+		h.Publish(TrackerEvent{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: next.TotalShines, Previous: strconv.Itoa(prev.TotalShines), Level: next.CurrentLevel, Episode: next.CurrentEpisode})
+	}
+}
+
+// handleEvents streams tracker events as Server-Sent Events.
+// Clients resume after a reconnect through the Last-Event-ID header (or ?lastEventId=).
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = r.URL.Query().Get("lastEventId")
+	}
+	// Fresh clients only get new events, reconnecting ones everything they missed
+	lastID := eventsNewOnly
+	if lastIDStr != "" {
+		var err error
+		lastID, err = strconv.ParseUint(strings.TrimSpace(lastIDStr), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	missed, ch, cancel := trackerEvents.Subscribe(lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, ev := range missed {
+		if err := writeSSE(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				// We were too slow, the client will reconnect with its last event ID
+				return
+			}
+			if err := writeSSE(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, ev TrackerEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestEventHubSubscribe(t *testing.T) {
+	tests := []struct {
+		name    string
+		afterID uint64
+		want    []uint64
+	}{
+		{"new client", eventsNewOnly, nil},
+		{"resume", 1, []uint64{2, 3}},
+		{"up to date", 3, nil},
+		{"ID from before a restart", 500, []uint64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewEventHub()
+			for i := 0; i < 3; i++ {
+				h.Publish(TrackerEvent{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: i})
+			}
+			missed, ch, cancel := h.Subscribe(tt.afterID)
+			defer cancel()
+			if len(missed) != len(tt.want) {
+				t.Fatalf("missed %+v, want IDs %v", missed, tt.want)
+			}
+			for i, id := range tt.want {
+				if missed[i].ID != id {
+					t.Errorf("missed[%d] has ID %d, want %d", i, missed[i].ID, id)
+				}
+			}
+
+			h.Publish(TrackerEvent{Type: EVENT_HOOK_LOST})
+			if ev := <-ch; ev.Type != EVENT_HOOK_LOST {
+				t.Errorf("next event = %+v, want %s", ev, EVENT_HOOK_LOST)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- Game Flags ---
+
+// The game keeps its boolean progress flags (the 0x10000 range of TFlagManager) as one bitfield.
+// Flag n lives in byte n/8, bit n%8. The first SHINE_FLAG_COUNT flags are the collected shines,
+// indexed by ShineDefinition.NumID. The blue coin flags follow them, see parseBlueCoinFlag.
+const (
+	SHINE_FLAG_COUNT = 120
+	NO_SHINE_ID      = 9999 // NumID placeholder for shines we have no hook mapping for yet
+)
+
+// flagSet reports whether flag n is set in the bitfield.
+func flagSet(flags []byte, n int) bool {
+	if n < 0 || n/8 >= len(flags) {
+		return false
+	}
+	return flags[n/8]&(1<<(n%8)) != 0
+}
+
+// indexShines builds the NumID -> shine ID lookup used to translate the shine flags.
+func (w *WorldData) indexShines() {
+	w.shinesByNumID = make(map[int][]string)
+	for _, zone := range w.Zones {
+		for _, shine := range zone.ShinesAvailable {
+			if shine.NumID == NO_SHINE_ID {
+				continue
+			}
+			ids := w.shinesByNumID[shine.NumID]
+			// Shines like the "secret" ones are listed in every episode of a zone
+			if !containsString(ids, shine.ID) {
+				w.shinesByNumID[shine.NumID] = append(ids, shine.ID)
+			}
+		}
+	}
+}
+
+// collectedShines returns the sorted IDs of all shines whose flag is set.
+func (w *WorldData) collectedShines(flags []byte) []string {
+	collected := make([]string, 0)
+	for numID, ids := range w.shinesByNumID {
+		if flagSet(flags, numID) {
+			collected = append(collected, ids...)
+		}
+	}
+	sort.Strings(collected)
+	return collected
+}
+
+// parseBlueCoinFlag extracts the flag index from a blue coin ID like "170b2":
+// the number before the "b" is the flag index, the digit after it the bit inside its byte.
+func parseBlueCoinFlag(id string) (int, bool) {
+	flagStr, bitStr, ok := strings.Cut(id, "b")
+	if !ok {
+		return 0, false
+	}
+	flag, err := strconv.Atoi(flagStr)
+	if err != nil || flag < SHINE_FLAG_COUNT {
+		return 0, false
+	}
+	bit, err := strconv.Atoi(bitStr)
+	if err != nil || bit != flag%8 {
+		return 0, false
+	}
+	return flag, true
+}
+
+// indexBlueCoins builds the blue coin ID -> flag index lookup. IDs that don't encode a flag are skipped.
+func (w *WorldData) indexBlueCoins() {
+	w.blueCoinFlags = make(map[string]int)
+	for _, bc := range w.BlueCoins {
+		if flag, ok := parseBlueCoinFlag(bc.ID); ok {
+			w.blueCoinFlags[bc.ID] = flag
+		}
+	}
+}
+
+// collectedBlueCoins returns the sorted IDs of all blue coins whose flag is set.
+func (w *WorldData) collectedBlueCoins(flags []byte) []string {
+	collected := make([]string, 0)
+	for id, flag := range w.blueCoinFlags {
+		if flagSet(flags, flag) {
+			collected = append(collected, id)
+		}
+	}
+	sort.Strings(collected)
+	return collected
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
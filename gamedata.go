@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// --- Game Data Overlay ---
+
+// gameDataReader returns the content of a game data file like "zones.json".
+type gameDataReader func(name string) ([]byte, error)
+
+// embeddedGameData reads the data files compiled into the binary.
+func embeddedGameData(name string) ([]byte, error) {
+	return dataEmbed.ReadFile("data/" + name)
+}
+
+// overlayGameData reads the embedded data files merged with the files of the same name in dir.
+// Without a dir only the embedded files are used.
+func overlayGameData(dir string) gameDataReader {
+	if dir == "" {
+		return embeddedGameData
+	}
+	return func(name string) ([]byte, error) {
+		base, baseErr := embeddedGameData(name)
+		path := filepath.Join(dir, name)
+		overlay, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return base, baseErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		if baseErr != nil {
+			return overlay, nil
+		}
+		merged, err := mergeGameData(base, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("merging %s: %w", path, err)
+		}
+		return merged, nil
+	}
+}
+
+// mergeGameData merges an overlay file into an embedded one, see mergeByID.
+func mergeGameData(base, overlay []byte) ([]byte, error) {
+	var baseDoc, overlayDoc any
+	for _, doc := range []struct {
+		data []byte
+		v    *any
+	}{{base, &baseDoc}, {overlay, &overlayDoc}} {
+		dec := json.NewDecoder(bytes.NewReader(doc.data))
+		dec.UseNumber() // Keep num_id and friends exactly as written
+		if err := dec.Decode(doc.v); err != nil {
+			return nil, err
+		}
+	}
+	return json.MarshalIndent(mergeByID(baseDoc, overlayDoc), "", "  ")
+}
+
+// mergeByID merges overlay into base. Objects are merged key by key and a null value removes the key
+// (e.g. a whole zone). Lists whose overlay entries all have an "id" are merged entry by entry on that ID,
+// new IDs are appended. Everything else is replaced by the overlay.
+func mergeByID(base, overlay any) any {
+	switch o := overlay.(type) {
+	case map[string]any:
+		b, ok := base.(map[string]any)
+		if !ok {
+			b = make(map[string]any)
+		}
+		for key, value := range o {
+			if value == nil {
+				delete(b, key)
+				continue
+			}
+			b[key] = mergeByID(b[key], value)
+		}
+		return b
+	case []any:
+		b, ok := base.([]any)
+		if !ok {
+			return o
+		}
+		for _, entry := range o {
+			if _, ok := entryID(entry); !ok {
+				return o
+			}
+		}
+		index := make(map[string]int)
+		for i, entry := range b {
+			if id, ok := entryID(entry); ok {
+				index[id] = i
+			}
+		}
+		for _, entry := range o {
+			id, _ := entryID(entry)
+			if i, ok := index[id]; ok {
+				b[i] = mergeByID(b[i], entry)
+			} else {
+				index[id] = len(b)
+				b = append(b, entry)
+			}
+		}
+		return b
+	}
+	return overlay
+}
+
+func entryID(entry any) (string, bool) {
+	obj, ok := entry.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	id, ok := obj["id"].(string)
+	return id, ok
+}
+
+// --- Game Data Reload ---
+
+// EntryDiff lists the IDs of added, removed and changed entries.
+type EntryDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+type DataDiff struct {
+	Zones     EntryDiff `json:"zones"`
+	Unlocks   EntryDiff `json:"unlocks"`
+	BlueCoins EntryDiff `json:"blue_coins"`
+	Stages    EntryDiff `json:"stages"`
+	Regions   EntryDiff `json:"regions"`
+}
+
+func (d DataDiff) empty() bool {
+	for _, e := range []EntryDiff{d.Zones, d.Unlocks, d.BlueCoins, d.Stages, d.Regions} {
+		if len(e.Added)+len(e.Removed)+len(e.Changed) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func diffEntries[T any](old, next map[string]T) EntryDiff {
+	diff := EntryDiff{Added: make([]string, 0), Removed: make([]string, 0), Changed: make([]string, 0)}
+	for id, entry := range next {
+		prev, ok := old[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, id)
+		case !reflect.DeepEqual(prev, entry):
+			diff.Changed = append(diff.Changed, id)
+		}
+	}
+	for id := range old {
+		if _, ok := next[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+func byID[T any](entries []T, id func(T) string) map[string]T {
+	m := make(map[string]T, len(entries))
+	for _, e := range entries {
+		m[id(e)] = e
+	}
+	return m
+}
+
+func diffWorlds(old, next *WorldData) DataDiff {
+	unlockID := func(u Unlock) string { return u.ID }
+	blueCoinID := func(bc BlueCoinDefinition) string { return bc.ID }
+	stageID := func(s StageDefinition) string { return s.ID }
+	regionID := func(r RegionDefinition) string { return r.ID }
+	return DataDiff{
+		Zones:     diffEntries(old.Zones, next.Zones),
+		Unlocks:   diffEntries(byID(old.Unlocks, unlockID), byID(next.Unlocks, unlockID)),
+		BlueCoins: diffEntries(byID(old.BlueCoins, blueCoinID), byID(next.BlueCoins, blueCoinID)),
+		Stages:    diffEntries(byID(old.Stages, stageID), byID(next.Stages, stageID)),
+		Regions:   diffEntries(byID(old.Regions, regionID), byID(next.Regions, regionID)),
+	}
+}
+
+// DataReloadResult is the answer of /api/data/reload.
+type DataReloadResult struct {
+	DataDiff
+	Problems []DataProblem `json:"problems"`
+}
+
+// handleDataReload re-reads the game data (POST) and reports what changed along with any inconsistencies.
+// If the files don't load, or have errors in strict mode, the current data is kept and the error is returned.
+func handleDataReload(overlayDir string, strict bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		world, err := parseGameData(overlayGameData(overlayDir))
+		if err != nil {
+			http.Error(w, "Game data not reloaded: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		result := DataReloadResult{DataDiff: diffWorlds(currentWorld(), &world), Problems: validateWorld(&world)}
+		if result.Problems == nil {
+			result.Problems = make([]DataProblem, 0)
+		}
+		if err := checkStrict(result.Problems); strict && err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		if !result.DataDiff.empty() {
+			setWorld(world)
+			fmt.Println("Game data reloaded.")
+			trackerEvents.Publish(TrackerEvent{Type: EVENT_DATA_RELOADED})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			http.Error(w, "Failed to encode diff", http.StatusInternalServerError)
+		}
+	}
+}
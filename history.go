@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Run History ---
+
+const HISTORY_DIR = "history"
+
+// Event types that end up in the per-seed history
+var historyEventTypes = map[string]bool{
+	EVENT_HOOK_CONNECTED:      true,
+	EVENT_HOOK_LOST:           true,
+	EVENT_LEVEL_CHANGED:       true,
+	EVENT_EPISODE_CHANGED:     true,
+	EVENT_ZONE_CHANGED:        true,
+	EVENT_SKILL_UNLOCKED:      true,
+	EVENT_SKILL_LOST:          true,
+	EVENT_SHINE_TOTAL_CHANGED: true,
+}
+
+// HistoryLog appends tracker events to one JSON Lines file per seed (history/<seed>.jsonl).
+type HistoryLog struct {
+	mu   sync.Mutex
+	dir  string
+	seed string // Seed the following events belong to, DEFAULT_SLOT until one was read
+}
+
+func NewHistoryLog(dir string) *HistoryLog {
+	return &HistoryLog{dir: dir, seed: DEFAULT_SLOT}
+}
+
+func (h *HistoryLog) path(seed string) string {
+	return filepath.Join(h.dir, safeFileName(seed)+".jsonl")
+}
+
+// Seed returns the seed events are currently recorded for.
+func (h *HistoryLog) Seed() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seed
+}
+
+// follow records every relevant tracker event from now on.
+func (h *HistoryLog) follow() {
+	trackerEvents.Listen(func(ev TrackerEvent) {
+		if ev.Type == EVENT_SEED_CHANGED {
+			h.mu.Lock()
+			h.seed = ev.Seed
+			h.mu.Unlock()
+			return
+		}
+		if !historyEventTypes[ev.Type] {
+			return
+		}
+		if err := h.record(ev); err != nil {
+			log.Printf("Error writing run history: %v", err)
+		}
+	})
+}
+
+func (h *HistoryLog) record(ev TrackerEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path(h.seed), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// HistoryFilter narrows down a history query. Zero values match everything.
+type HistoryFilter struct {
+	Types map[string]bool
+	Since time.Time
+	Until time.Time
+}
+
+func (f HistoryFilter) matches(ev TrackerEvent) bool {
+	if len(f.Types) > 0 && !f.Types[ev.Type] {
+		return false
+	}
+	if !f.Since.IsZero() && ev.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && ev.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Query returns all recorded events of a seed that match the filter, oldest first.
+func (h *HistoryLog) Query(seed string, filter HistoryFilter) ([]TrackerEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make([]TrackerEvent, 0)
+	f, err := os.Open(h.path(seed))
+	if os.IsNotExist(err) {
+		return events, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev TrackerEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			// A crash can leave a half written last line, skip it
+			continue
+		}
+		if filter.matches(ev) {
+			events = append(events, ev)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// handleHistory serves the run history:
+// /api/history?seed=<seed>&type=skill_unlocked,level_changed&since=<RFC3339>&until=<RFC3339>
+// Without a seed the history of the seed currently played is returned.
+func handleHistory(history *HistoryLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		seed := q.Get("seed")
+		if seed == "" {
+			seed = history.Seed()
+		}
+
+		var filter HistoryFilter
+		if types := q.Get("type"); types != "" {
+			filter.Types = make(map[string]bool)
+			for _, t := range strings.Split(types, ",") {
+				filter.Types[strings.TrimSpace(t)] = true
+			}
+		}
+		for param, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+			value := q.Get(param)
+			if value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "Invalid "+param+" parameter, expected an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*target = t
+		}
+
+		events, err := history.Query(seed, filter)
+		if err != nil {
+			http.Error(w, "Failed to read history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		resp := struct {
+			Seed   string         `json:"seed"`
+			Events []TrackerEvent `json:"events"`
+		}{Seed: seed, Events: events}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode history", http.StatusInternalServerError)
+		}
+	}
+}
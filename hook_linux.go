@@ -4,41 +4,208 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
 	"unsafe"
 )
 
+// Process names are matched against comm (cut to 15 characters by the kernel), the executable and argv[0].
+// The command line patterns find Dolphin started through Flatpak or from an AppImage under another name.
+var (
+	defaultDolphinProcessNames    = []string{"dolphin-emu", "dolphin-emu-nogui", "dolphin-emu-qt*", "dolphin"}
+	defaultDolphinCmdlinePatterns = []string{`org\.DolphinEmu\.dolphin-emu`, `(?i)dolphin[^/ ]*\.AppImage`}
+)
+
+var flatpakScope = regexp.MustCompile(`app-flatpak-(.+?)-\d+\.scope`)
+
 type iovec struct {
 	addr uintptr
 	len  uint
 }
 
-// Read reads memory from the Dolphin emulator using process_vm_readv.
-func (d *DolphinHookManager) Read(gcAddress uint32, size int) ([]byte, error) {
-	if !d.IsHooked {
+// dolphinProcess is the MemorySource for a running Dolphin process.
+type dolphinProcess struct {
+	PID      uint32
+	BaseAddr uintptr
+	GameID   string
+	lastPID  uint32 // Kept across Close, so a reconnect prefers the same instance
+	lastErr  string // Last reason Open failed, printed once per change
+	memory   *processMemory
+}
+
+// Ways of reading another process's memory. process_vm_readv is preferred, /proc/<pid>/mem
+// works where seccomp filters (containers, hardened kernels) block the syscall.
+const (
+	READ_METHOD_VM_READV = "process_vm_readv"
+	READ_METHOD_PROC_MEM = "/proc/pid/mem"
+)
+
+// Read reads memory from the Dolphin emulator with the method Open found to work.
+func (d *dolphinProcess) Read(gcAddress uint32, size int) ([]byte, error) {
+	if d.PID == 0 {
 		return nil, fmt.Errorf("not hooked")
 	}
+	if !currentPin().allows(d.PID, d.GameID) {
+		return nil, errPinChanged
+	}
+	return d.memory.read(d.BaseAddr+uintptr(gcAddress&0x7FFFFFFF), size)
+}
+
+// processMemory reads the memory of another process with one of the READ_METHOD_*.
+type processMemory struct {
+	pid    uint32
+	method string
+	mem    *os.File // /proc/<pid>/mem for READ_METHOD_PROC_MEM
+}
+
+// vmReadv reads with process_vm_readv. Tests replace it to act like a seccomp filter that blocks the syscall.
+var vmReadv = readProcessMemory
+
+// openProcessMemory picks the first method that can read addr of the process. If none works, the
+// error is the one of process_vm_readv, unless the syscall itself is unavailable.
+func openProcessMemory(pid uint32, addr uintptr) (*processMemory, error) {
+	_, vmErr := vmReadv(pid, addr, GAME_ID_SIZE)
+	if vmErr == nil {
+		return &processMemory{pid: pid, method: READ_METHOD_VM_READV}, nil
+	}
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", pid))
+	if err == nil {
+		if _, err = readProcMem(mem, addr, GAME_ID_SIZE); err == nil {
+			return &processMemory{pid: pid, method: READ_METHOD_PROC_MEM, mem: mem}, nil
+		}
+		mem.Close()
+	}
+	if errors.Is(vmErr, syscall.ENOSYS) {
+		return nil, err
+	}
+	return nil, vmErr
+}
 
-	realAddr := d.BaseAddr + uintptr(gcAddress&0x7FFFFFFF)
+func (p *processMemory) read(addr uintptr, size int) ([]byte, error) {
+	if p.mem != nil {
+		return readProcMem(p.mem, addr, size)
+	}
+	return vmReadv(p.pid, addr, size)
+}
+
+func (p *processMemory) close() {
+	if p.mem != nil {
+		p.mem.Close()
+	}
+}
+
+// readProcessMemory reads with the process_vm_readv syscall.
+func readProcessMemory(pid uint32, addr uintptr, size int) ([]byte, error) {
 	buffer := make([]byte, size)
 
 	localIov := iovec{addr: uintptr(unsafe.Pointer(&buffer[0])), len: uint(size)}
-	remoteIov := iovec{addr: realAddr, len: uint(size)}
+	remoteIov := iovec{addr: addr, len: uint(size)}
 
-	// 310 is the syscall number for process_vm_readv on x86_64
-	_, _, errno := syscall.Syscall6(310, uintptr(d.PID), uintptr(unsafe.Pointer(&localIov)), 1, uintptr(unsafe.Pointer(&remoteIov)), 1, 0)
+	n, _, errno := syscall.Syscall6(sysProcessVMReadv, uintptr(pid), uintptr(unsafe.Pointer(&localIov)), 1, uintptr(unsafe.Pointer(&remoteIov)), 1, 0)
 
 	if errno != 0 {
 		return nil, errno
 	}
+	if int(n) < size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buffer, nil
+}
+
+// readProcMem reads with pread on an open /proc/<pid>/mem.
+func readProcMem(mem *os.File, addr uintptr, size int) ([]byte, error) {
+	buffer := make([]byte, size)
+	if _, err := mem.ReadAt(buffer, int64(addr)); err != nil {
+		return nil, err
+	}
 	return buffer, nil
 }
 
-func findDolphinPID() uint32 {
+// procInfo is what /proc tells about a process that matters for recognizing Dolphin.
+type procInfo struct {
+	comm, exe, argv0, cmdline string
+	ppid                      uint32
+}
+
+func readProcInfo(pid int) procInfo {
+	var info procInfo
+	comm, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	info.comm = strings.TrimSpace(string(comm))
+	// Only readable for processes of the same user, the others just can't match by executable
+	info.exe, _ = os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	cmdline, _ := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+	info.argv0, info.cmdline = args[0], strings.Join(args, " ")
+	// The parent PID is the second field after the command name, which may itself contain spaces and parentheses
+	if stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		if i := bytes.LastIndexByte(stat, ')'); i >= 0 {
+			if fields := strings.Fields(string(stat[i+1:])); len(fields) > 1 {
+				ppid, _ := strconv.ParseUint(fields[1], 10, 32)
+				info.ppid = uint32(ppid)
+			}
+		}
+	}
+	return info
+}
+
+func (p procInfo) isDolphin(m *ProcessMatcher) bool {
+	return m.matchName(p.comm, filepath.Base(p.exe), filepath.Base(p.argv0)) || m.matchCmdline(p.cmdline)
+}
+
+// detectSandbox tells whether a process runs inside Flatpak, Snap or an AppImage, and the Flatpak app ID.
+func detectSandbox(pid uint32, info procInfo) (sandbox, appID string) {
+	cgroup, _ := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if m := flatpakScope.FindSubmatch(cgroup); m != nil {
+		return SANDBOX_FLATPAK, string(m[1])
+	}
+	if _, err := os.Stat(fmt.Sprintf("/proc/%d/root/.flatpak-info", pid)); err == nil {
+		return SANDBOX_FLATPAK, ""
+	}
+	if bytes.Contains(cgroup, []byte("/snap.")) {
+		return SANDBOX_SNAP, ""
+	}
+	// AppImages run from a FUSE mount named /tmp/.mount_<name>
+	if strings.Contains(info.exe, "/.mount_") || strings.Contains(info.argv0, "/.mount_") {
+		return SANDBOX_APPIMAGE, ""
+	}
+	return "", ""
+}
+
+// explainAccessError turns a permission error on another process into advice on how to allow the access.
+func explainAccessError(err error, sandbox, appID string) string {
+	if !errors.Is(err, fs.ErrPermission) {
+		return err.Error()
+	}
+	if sandbox == SANDBOX_FLATPAK {
+		if appID == "" {
+			appID = "the app"
+		}
+		return fmt.Sprintf("%v: Dolphin runs in a Flatpak sandbox (%s), which keeps other programs from reading its memory. "+
+			"Run the tracker as root or give it the ptrace capability (sudo setcap cap_sys_ptrace+ep <tracker binary>), "+
+			"or use a native or AppImage build of Dolphin", err, appID)
+	}
+	if scope, _ := os.ReadFile("/proc/sys/kernel/yama/ptrace_scope"); len(scope) > 0 && scope[0] != '0' {
+		return fmt.Sprintf("%v: kernel.yama.ptrace_scope is %s, so only parent processes may read Dolphin's memory. "+
+			"Run the tracker as root, give it the ptrace capability (sudo setcap cap_sys_ptrace+ep <tracker binary>) "+
+			"or allow it for everyone with sudo sysctl kernel.yama.ptrace_scope=0", err, strings.TrimSpace(string(scope)))
+	}
+	return fmt.Sprintf("%v: Dolphin probably runs as another user, start the tracker as the same user or as root", err)
+}
+
+// listDolphinInstances returns every Dolphin process with the location of its emulated RAM and the game ID.
+func listDolphinInstances() []DolphinInstance {
+	matcher := currentMatcher()
+	var instances []DolphinInstance
+	parents := make(map[uint32]uint32) // Of every process, to find the launchers below
 	files, _ := os.ReadDir("/proc")
 	for _, f := range files {
 		if !f.IsDir() {
@@ -49,51 +216,183 @@ func findDolphinPID() uint32 {
 			continue
 		}
 
-		comm, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
-		// Linux process names are often lowercase
-		name := strings.TrimSpace(string(comm))
-		if name == "dolphin-emu" || name == "dolphin" {
-			return uint32(pid)
+		info := readProcInfo(pid)
+		parents[uint32(pid)] = info.ppid
+		if !info.isDolphin(matcher) {
+			continue
+		}
+
+		inst := DolphinInstance{PID: uint32(pid), Name: info.comm}
+		sandbox, appID := detectSandbox(inst.PID, info)
+		inst.Sandbox = sandbox
+		if err := findRAM(&inst); err != nil {
+			inst.Error = explainAccessError(err, sandbox, appID)
+		}
+		instances = append(instances, inst)
+	}
+
+	// The command line patterns also match launchers (flatpak run, bwrap, the AppImage runtime).
+	// One without emulated RAM is dropped if another matched process runs below it.
+	matched := make(map[uint32]bool)
+	for _, inst := range instances {
+		matched[inst.PID] = true
+	}
+	launchers := make(map[uint32]bool)
+	for _, inst := range instances {
+		for ppid, depth := parents[inst.PID], 0; ppid > 1 && depth < 32; ppid, depth = parents[ppid], depth+1 {
+			if matched[ppid] {
+				launchers[ppid] = true
+			}
+		}
+	}
+	kept := instances[:0]
+	for _, inst := range instances {
+		if inst.RAMBase != 0 || !launchers[inst.PID] {
+			kept = append(kept, inst)
+		}
+	}
+	return kept
+}
+
+// findRAM sets the base of the emulated GameCube RAM, the ID of the game in it and the read method that works.
+// Other buffers of Dolphin can have the same size, so the mapping starting with a game header wins.
+// While no game runs it is the first one.
+func findRAM(inst *DolphinInstance) error {
+	bases, err := findRAMMappings(inst.PID)
+	if err != nil {
+		return err
+	}
+	memory, err := openProcessMemory(inst.PID, bases[0])
+	if err != nil {
+		return fmt.Errorf("reading the game ID: %w", err)
+	}
+	defer memory.close()
+	inst.RAMBase, inst.ReadMethod = bases[0], memory.method
+	for _, base := range bases {
+		header, err := memory.read(base, GAME_ID_SIZE)
+		if err != nil {
+			return fmt.Errorf("reading the game ID: %w", err)
+		}
+		if id := parseGameID(header); id != "" {
+			inst.RAMBase, inst.GameID = base, id
+			break
 		}
 	}
-	return 0
+	return nil
 }
 
-func (d *DolphinHookManager) Hook() bool {
-	pid := findDolphinPID()
-	if pid == 0 {
-		return false
+// findRAMMappings lists the mappings in /proc/<pid>/maps that have the size of the emulated GameCube RAM.
+func findRAMMappings(pid uint32) ([]uintptr, error) {
+	mappings, err := readMaps(pid)
+	if err != nil {
+		return nil, err
 	}
+	var bases []uintptr
+	for _, m := range mappings {
+		// We look for the 32MB GameCube RAM mapping (rw-p)
+		if m.perms == "rw-p" && m.end-m.start == 0x2000000 {
+			bases = append(bases, uintptr(m.start))
+		}
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("no GameCube RAM mapping found in process %d", pid)
+	}
+	return bases, nil
+}
+
+// procMapping is a line of /proc/<pid>/maps.
+type procMapping struct {
+	start, end uint64
+	perms      string
+	path       string
+}
 
+func readMaps(pid uint32) ([]procMapping, error) {
 	f, err := os.Open(fmt.Sprintf("/proc/%d/maps", pid))
 	if err != nil {
-		return false
+		return nil, err
 	}
 	defer f.Close()
 
+	var mappings []procMapping
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
-		line := scanner.Text()
-		// We look for the 32MB GameCube RAM mapping (rw-p)
-		if strings.Contains(line, "rw-p") {
-			parts := strings.Fields(line)
-			addrRange := strings.Split(parts[0], "-")
-			start, _ := strconv.ParseUint(addrRange[0], 16, 64)
-			end, _ := strconv.ParseUint(addrRange[1], 16, 64)
-
-			if end-start == 0x2000000 {
-				d.PID = pid
-				d.BaseAddr = uintptr(start)
-				d.IsHooked = true
-				return true
-			}
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+		addrRange := strings.Split(parts[0], "-")
+		if len(addrRange) != 2 {
+			continue
+		}
+		m := procMapping{perms: parts[1]}
+		m.start, _ = strconv.ParseUint(addrRange[0], 16, 64)
+		m.end, _ = strconv.ParseUint(addrRange[1], 16, 64)
+		if len(parts) >= 6 {
+			m.path = strings.Join(parts[5:], " ")
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, scanner.Err()
+}
+
+// ramCandidates lists the mappings of at least 16 MiB, one of which should be the emulated RAM.
+func ramCandidates(pid uint32) ([]MemoryMapping, error) {
+	mappings, err := readMaps(pid)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []MemoryMapping
+	for _, m := range mappings {
+		if size := m.end - m.start; size >= 0x1000000 {
+			candidates = append(candidates, MemoryMapping{Start: fmt.Sprintf("0x%X", m.start), Size: size,
+				Perms: m.perms, Path: m.path, RAMSize: size == 0x2000000})
 		}
 	}
-	return false
+	return candidates, nil
 }
 
-func (d *DolphinHookManager) Close() {
-	// Linux implementation currently doesn't keep a persistent handle open
-	// so we just reset the field.
-	d.Handle = 0
+// readProcessHeader returns the raw game header at the RAM base of an instance.
+func readProcessHeader(inst DolphinInstance) ([]byte, error) {
+	memory, err := openProcessMemory(inst.PID, inst.RAMBase)
+	if err != nil {
+		return nil, err
+	}
+	defer memory.close()
+	return memory.read(inst.RAMBase, GAME_ID_SIZE)
+}
+
+// Open attaches to the Dolphin instance chosen by chooseInstance.
+func (d *dolphinProcess) Open() error {
+	inst, err := chooseInstance(listDolphinInstances(), currentPin(), d.lastPID)
+	if err != nil {
+		// Open is retried every second, so only changes are worth telling
+		if err.Error() != d.lastErr {
+			fmt.Printf("Dolphin not hooked: %v\n", err)
+			d.lastErr = err.Error()
+		}
+		return err
+	}
+	memory, err := openProcessMemory(inst.PID, inst.RAMBase)
+	if err != nil {
+		return err
+	}
+	if memory.method != READ_METHOD_VM_READV {
+		fmt.Printf("process_vm_readv is not available, reading Dolphin's memory through /proc/%d/mem.\n", inst.PID)
+	}
+	d.lastErr = ""
+	d.PID, d.BaseAddr, d.GameID, d.memory = inst.PID, inst.RAMBase, inst.GameID, memory
+	d.lastPID = inst.PID
+	attachedInstance.Store(inst.PID)
+	return nil
+}
+
+func (d *dolphinProcess) Close() {
+	if d.memory != nil {
+		d.memory.close()
+		d.memory = nil
+	}
+	d.PID = 0
+	d.BaseAddr = 0
+	attachedInstance.Store(0)
 }
@@ -0,0 +1,139 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+const (
+	HELPER_PROCESS_ENV = "SMS_TRACKER_HELPER_PROCESS"
+	HELPER_MARKER      = "GMSE01 marker of the helper process"
+)
+
+// TestHelperProcess isn't a test. Started by startHelperProcess, it keeps a marker in memory, prints its address
+// and waits until its stdin is closed.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(HELPER_PROCESS_ENV) != "1" {
+		return
+	}
+	marker := []byte(HELPER_MARKER)
+	fmt.Printf("0x%X\n", uintptr(unsafe.Pointer(&marker[0])))
+	bufio.NewReader(os.Stdin).ReadString('\n')
+	runtime.KeepAlive(marker)
+	os.Exit(0)
+}
+
+// startHelperProcess runs TestHelperProcess in a child and returns its PID and the address of the marker.
+func startHelperProcess(t *testing.T) (uint32, uintptr) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), HELPER_PROCESS_ENV+"=1")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the marker address of the helper process: %v", err)
+	}
+	addr, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(line), "0x"), 16, 64)
+	if err != nil {
+		t.Fatalf("helper process printed %q: %v", line, err)
+	}
+	return uint32(cmd.Process.Pid), uintptr(addr)
+}
+
+func TestReadProcMem(t *testing.T) {
+	pid, addr := startHelperProcess(t)
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", pid))
+	if err != nil {
+		t.Skipf("/proc/%d/mem can't be opened here: %v", pid, err)
+	}
+	defer mem.Close()
+
+	data, err := readProcMem(mem, addr, len(HELPER_MARKER))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != HELPER_MARKER {
+		t.Errorf("read %q, want %q", data, HELPER_MARKER)
+	}
+	if _, err := readProcMem(mem, 0, GAME_ID_SIZE); err == nil {
+		t.Error("reading unmapped memory succeeded")
+	}
+}
+
+func TestOpenProcessMemory(t *testing.T) {
+	pid, addr := startHelperProcess(t)
+	tests := []struct {
+		name    string
+		blocked bool // process_vm_readv fails like under a seccomp filter
+		method  string
+	}{
+		{"process_vm_readv", false, READ_METHOD_VM_READV},
+		{"blocked syscall", true, READ_METHOD_PROC_MEM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.blocked {
+				vmReadv = func(uint32, uintptr, int) ([]byte, error) { return nil, syscall.EPERM }
+				t.Cleanup(func() { vmReadv = readProcessMemory })
+			} else if _, err := readProcessMemory(pid, addr, GAME_ID_SIZE); err != nil {
+				t.Skipf("process_vm_readv isn't available here: %v", err)
+			}
+
+			memory, err := openProcessMemory(pid, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer memory.close()
+			if memory.method != tt.method {
+				t.Errorf("read method = %q, want %q", memory.method, tt.method)
+			}
+			data, err := memory.read(addr, len(HELPER_MARKER))
+			if err != nil || !bytes.Equal(data, []byte(HELPER_MARKER)) {
+				t.Errorf("read = %q, %v; want %q", data, err, HELPER_MARKER)
+			}
+		})
+	}
+}
+
+func TestOpenProcessMemoryFails(t *testing.T) {
+	pid, _ := startHelperProcess(t)
+	// Neither method can read unmapped memory, the error is the one of process_vm_readv
+	vmReadv = func(uint32, uintptr, int) ([]byte, error) { return nil, syscall.EPERM }
+	defer func() { vmReadv = readProcessMemory }()
+	if _, err := openProcessMemory(pid, 0); !errors.Is(err, syscall.EPERM) {
+		t.Errorf("error = %v, want %v", err, syscall.EPERM)
+	}
+
+	// Without the syscall the error of /proc/<pid>/mem is more useful
+	vmReadv = func(uint32, uintptr, int) ([]byte, error) { return nil, syscall.ENOSYS }
+	if _, err := openProcessMemory(pid, 0); err == nil || errors.Is(err, syscall.ENOSYS) {
+		t.Errorf("error = %v, want the error of /proc/%d/mem", err, pid)
+	}
+}
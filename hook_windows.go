@@ -18,11 +18,30 @@ var (
 	procGetModuleBaseName = modkernel32.NewProc("K32GetModuleBaseNameW")
 )
 
+// Windows doesn't expose the command line of other processes easily, so only the module name is matched
+var (
+	defaultDolphinProcessNames    = []string{"Dolphin.exe"}
+	defaultDolphinCmdlinePatterns []string
+)
+
+// dolphinProcess is the MemorySource for a running Dolphin process.
+type dolphinProcess struct {
+	PID      uint32
+	Handle   uintptr
+	BaseAddr uintptr
+	GameID   string
+	lastPID  uint32 // Kept across Close, so a reconnect prefers the same instance
+	lastErr  string // Last reason Open failed, printed once per change
+}
+
 // Read reads memory from the Dolphin emulator at the specified GameCube address.
-func (d *DolphinHookManager) Read(gcAddress uint32, size int) ([]byte, error) {
-	if !d.IsHooked {
+func (d *dolphinProcess) Read(gcAddress uint32, size int) ([]byte, error) {
+	if d.Handle == 0 {
 		return nil, fmt.Errorf("not hooked")
 	}
+	if !currentPin().allows(d.PID, d.GameID) {
+		return nil, errPinChanged
+	}
 	realAddr := d.BaseAddr + uintptr(gcAddress&0x7FFFFFFF)
 	buffer := make([]byte, size)
 	var read int
@@ -33,35 +52,87 @@ func (d *DolphinHookManager) Read(gcAddress uint32, size int) ([]byte, error) {
 	return buffer, nil
 }
 
-// getEmuRAMBase scans the Dolphin process memory to find the base address of the emulated GameCube RAM.
-func getEmuRAMBase(hProcess syscall.Handle) uintptr {
+// MEMORY_BASIC_INFORMATION is what VirtualQueryEx reports about a memory region.
+type MEMORY_BASIC_INFORMATION struct {
+	BaseAddr, AllocBase uintptr
+	AllocProt           uint32
+	RegionSize          uintptr
+	State, Prot, Type   uint32
+}
+
+// getEmuRAMBase scans the Dolphin process memory to find the base address of the emulated GameCube RAM
+// and the ID of the game in it. The RAM is only recognized while a game is running.
+func getEmuRAMBase(hProcess syscall.Handle) (uintptr, string) {
 	var address uintptr
-	type MBI struct {
-		BaseAddr, AllocBase uintptr
-		AllocProt           uint32
-		RegionSize          uintptr
-		State, Prot, Type   uint32
-	}
-	var mbi MBI
+	var mbi MEMORY_BASIC_INFORMATION
 	for {
 		ret, _, _ := procVirtualQueryEx.Call(uintptr(hProcess), address, uintptr(unsafe.Pointer(&mbi)), unsafe.Sizeof(mbi))
 		if ret == 0 {
 			break
 		}
 		if mbi.RegionSize == 0x2000000 {
-			buf := make([]byte, 3)
+			buf := make([]byte, GAME_ID_SIZE)
 			var read int
-			procReadProcessMemory.Call(uintptr(hProcess), mbi.BaseAddr, uintptr(unsafe.Pointer(&buf[0])), 3, uintptr(unsafe.Pointer(&read)))
-			if string(buf) == "GMS" {
-				return mbi.BaseAddr
+			procReadProcessMemory.Call(uintptr(hProcess), mbi.BaseAddr, uintptr(unsafe.Pointer(&buf[0])), GAME_ID_SIZE, uintptr(unsafe.Pointer(&read)))
+			if id := parseGameID(buf); id != "" {
+				return mbi.BaseAddr, id
 			}
 		}
 		address += mbi.RegionSize
 	}
-	return 0
+	return 0, ""
+}
+
+// Memory region types reported by VirtualQueryEx
+const (
+	MEM_IMAGE   = 0x1000000
+	MEM_MAPPED  = 0x40000
+	MEM_PRIVATE = 0x20000
+)
+
+// ramCandidates lists the memory regions of at least 16 MiB, one of which should be the emulated RAM.
+func ramCandidates(pid uint32) ([]MemoryMapping, error) {
+	hProcess, err := syscall.OpenProcess(PROCESS_VM_READ|PROCESS_QUERY_INFORMATION, false, pid)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(hProcess)
+
+	var mbi MEMORY_BASIC_INFORMATION
+	var candidates []MemoryMapping
+	for address := uintptr(0); ; address += mbi.RegionSize {
+		ret, _, _ := procVirtualQueryEx.Call(uintptr(hProcess), address, uintptr(unsafe.Pointer(&mbi)), unsafe.Sizeof(mbi))
+		if ret == 0 {
+			break
+		}
+		if mbi.RegionSize < 0x1000000 {
+			continue
+		}
+		kind := map[uint32]string{MEM_IMAGE: "image", MEM_MAPPED: "mapped", MEM_PRIVATE: "private"}[mbi.Type]
+		candidates = append(candidates, MemoryMapping{Start: fmt.Sprintf("0x%X", mbi.BaseAddr), Size: uint64(mbi.RegionSize),
+			Perms: fmt.Sprintf("0x%X", mbi.Prot), Path: kind, RAMSize: mbi.RegionSize == 0x2000000})
+	}
+	return candidates, nil
+}
+
+// readProcessHeader returns the raw game header at the RAM base of an instance.
+func readProcessHeader(inst DolphinInstance) ([]byte, error) {
+	hProcess, err := syscall.OpenProcess(PROCESS_VM_READ|PROCESS_QUERY_INFORMATION, false, inst.PID)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(hProcess)
+	buf := make([]byte, GAME_ID_SIZE)
+	var read int
+	ret, _, err := procReadProcessMemory.Call(uintptr(hProcess), inst.RAMBase, uintptr(unsafe.Pointer(&buf[0])), GAME_ID_SIZE, uintptr(unsafe.Pointer(&read)))
+	if ret == 0 {
+		return nil, err
+	}
+	return buf, nil
 }
 
-func findDolphinPID() uint32 {
+// listDolphinInstances returns every Dolphin process with the location of its emulated RAM and the game ID.
+func listDolphinInstances() []DolphinInstance {
 	var pids [1024]uint32
 	var cb uint32
 
@@ -74,9 +145,11 @@ func findDolphinPID() uint32 {
 
 	if ret == 0 {
 		fmt.Printf("Fatal: Could not enumerate processes. Error: %v\n", err)
-		return 0
+		return nil
 	}
 
+	matcher := currentMatcher()
+	var instances []DolphinInstance
 	// cb is the number of bytes returned. Each PID is 4 bytes.
 	count := cb / 4
 	for i := uint32(0); i < count; i++ {
@@ -114,39 +187,47 @@ func findDolphinPID() uint32 {
 			uintptr(len(name)),
 		)
 
-		syscall.CloseHandle(syscall.Handle(h))
-
-		if nRet != 0 {
-			processName := syscall.UTF16ToString(name[:])
-			if processName == "Dolphin.exe" {
-				return pid
+		if moduleName := syscall.UTF16ToString(name[:]); nRet != 0 && matcher.matchName(moduleName) {
+			inst := DolphinInstance{PID: pid, Name: moduleName}
+			inst.RAMBase, inst.GameID = getEmuRAMBase(syscall.Handle(h))
+			if inst.RAMBase == 0 {
+				inst.Error = fmt.Sprintf("no GameCube RAM found in process %d", pid)
+			} else {
+				inst.ReadMethod = "ReadProcessMemory"
 			}
+			instances = append(instances, inst)
 		}
+		syscall.CloseHandle(syscall.Handle(h))
 	}
-
-	fmt.Println("Dolphin.exe was not found. Ensure it is running.")
-	return 0
+	return instances
 }
 
-// Hook attempts to connect to the Dolphin emulator and locate the game's RAM.
-func (d *DolphinHookManager) Hook() bool {
-	pid := findDolphinPID()
-	if pid == 0 {
-		return false
+// Open attaches to the Dolphin instance chosen by chooseInstance.
+func (d *dolphinProcess) Open() error {
+	inst, err := chooseInstance(listDolphinInstances(), currentPin(), d.lastPID)
+	if err != nil {
+		// Open is retried every second, so only changes are worth telling
+		if err.Error() != d.lastErr {
+			fmt.Printf("Dolphin not hooked: %v\n", err)
+			d.lastErr = err.Error()
+		}
+		return err
 	}
-	hProcess, _ := syscall.OpenProcess(PROCESS_VM_READ|PROCESS_QUERY_INFORMATION, false, pid)
-	base := getEmuRAMBase(hProcess)
-	if base == 0 {
-		syscall.CloseHandle(hProcess)
-		return false
+	hProcess, err := syscall.OpenProcess(PROCESS_VM_READ|PROCESS_QUERY_INFORMATION, false, inst.PID)
+	if err != nil {
+		return err
 	}
-	d.PID, d.Handle, d.BaseAddr, d.IsHooked = pid, uintptr(hProcess), base, true
-	return true
+	d.lastErr = ""
+	d.PID, d.Handle, d.BaseAddr, d.GameID = inst.PID, uintptr(hProcess), inst.RAMBase, inst.GameID
+	d.lastPID = inst.PID
+	attachedInstance.Store(inst.PID)
+	return nil
 }
 
-func (d *DolphinHookManager) Close() {
+func (d *dolphinProcess) Close() {
 	if d.Handle != 0 {
 		syscall.CloseHandle(syscall.Handle(d.Handle))
 		d.Handle = 0
 	}
+	attachedInstance.Store(0)
 }
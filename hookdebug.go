@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --- Hook Diagnostics ---
+
+// HOOK_LATENCY_SAMPLES is how many of the latest read durations the percentiles are computed from.
+const HOOK_LATENCY_SAMPLES = 512
+
+// HookStats counts hook attempts and reads of a DolphinHookManager. Unlike the rest of the manager
+// it is safe for concurrent use, so /api/debug/hook can look at it while the scanner runs.
+type HookStats struct {
+	mu            sync.Mutex
+	attempts      int
+	lastHook      time.Time
+	lastHookError string
+	reads         int
+	failedReads   int
+	lastSuccess   time.Time
+	lastReadError error
+	lastErrorAt   time.Time
+	latencies     [HOOK_LATENCY_SAMPLES]time.Duration // Ring buffer
+	samples       int
+}
+
+func (s *HookStats) recordHook(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if err != nil {
+		s.lastHookError = err.Error()
+		return
+	}
+	s.lastHook, s.lastHookError = time.Now(), ""
+}
+
+func (s *HookStats) recordRead(took time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	s.latencies[s.samples%HOOK_LATENCY_SAMPLES] = took
+	s.samples++
+	if err != nil {
+		s.failedReads++
+		s.lastReadError, s.lastErrorAt = err, time.Now()
+		return
+	}
+	s.lastSuccess = time.Now()
+}
+
+// LatencyPercentiles are read durations in microseconds.
+type LatencyPercentiles struct {
+	Samples int   `json:"samples"`
+	P50     int64 `json:"p50_us"`
+	P90     int64 `json:"p90_us"`
+	P99     int64 `json:"p99_us"`
+	Max     int64 `json:"max_us"`
+}
+
+// ReadErrorInfo is the last failed read. Errno is the system error number, 0 if the error had none.
+type ReadErrorInfo struct {
+	Message string    `json:"message"`
+	Errno   uintptr   `json:"errno,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// HookStatsReport is the JSON form of HookStats.
+type HookStatsReport struct {
+	Attempts      int                `json:"hook_attempts"`
+	LastHook      *time.Time         `json:"last_hook,omitempty"`
+	LastHookError string             `json:"last_hook_error,omitempty"`
+	Reads         int                `json:"reads"`
+	FailedReads   int                `json:"failed_reads"`
+	LastSuccess   *time.Time         `json:"last_success,omitempty"`
+	SinceSuccess  *int64             `json:"ms_since_last_success,omitempty"` // Unset if no read ever succeeded
+	LastReadError *ReadErrorInfo     `json:"last_read_error,omitempty"`
+	Latency       LatencyPercentiles `json:"read_latency"`
+}
+
+func (s *HookStats) report() HookStatsReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := HookStatsReport{Attempts: s.attempts, LastHookError: s.lastHookError, Reads: s.reads, FailedReads: s.failedReads}
+	if !s.lastHook.IsZero() {
+		r.LastHook = &s.lastHook
+	}
+	if !s.lastSuccess.IsZero() {
+		since := time.Since(s.lastSuccess).Milliseconds()
+		r.LastSuccess, r.SinceSuccess = &s.lastSuccess, &since
+	}
+	if s.lastReadError != nil {
+		r.LastReadError = &ReadErrorInfo{Message: s.lastReadError.Error(), At: s.lastErrorAt}
+		var errno syscall.Errno
+		if errors.As(s.lastReadError, &errno) {
+			r.LastReadError.Errno = uintptr(errno)
+		}
+	}
+
+	n := min(s.samples, HOOK_LATENCY_SAMPLES)
+	sorted := append([]time.Duration(nil), s.latencies[:n]...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	r.Latency.Samples = n
+	if n > 0 {
+		at := func(p int) int64 { return sorted[(n-1)*p/100].Microseconds() }
+		r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max = at(50), at(90), at(99), sorted[n-1].Microseconds()
+	}
+	return r
+}
+
+// LOCATION_MAX_CANDIDATES limits how many level name occurrences a scan keeps for the diagnostics.
+const LOCATION_MAX_CANDIDATES = 64
+
+// LocationCandidate is an occurrence of a level name the last full scan of SyncLocation looked at.
+type LocationCandidate struct {
+	Level    string `json:"level"`
+	Address  string `json:"address"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"` // Why it was skipped
+}
+
+// LocationScan is what the last full scan considered. A new scan replaces it as a whole,
+// so snapshots can share the slices.
+type LocationScan struct {
+	ScannedAt  time.Time           `json:"scanned_at,omitzero"`
+	Candidates []LocationCandidate `json:"candidates"`
+	Missions   []string            `json:"missions"` // Possible mission titles before the accepted level name, the first one is used
+}
+
+// MemoryMapping is a memory region of a Dolphin process that could hold the emulated RAM.
+type MemoryMapping struct {
+	Start   string `json:"start"`
+	Size    uint64 `json:"size"`
+	Perms   string `json:"perms,omitempty"`
+	Path    string `json:"path,omitempty"`
+	RAMSize bool   `json:"ram_size"` // Exactly the 32 MiB Dolphin allocates for main memory
+}
+
+// ProcessDiagnostics is what the hook sees of one Dolphin process.
+type ProcessDiagnostics struct {
+	PID         uint32          `json:"pid"`
+	Name        string          `json:"name"`
+	Sandbox     string          `json:"sandbox,omitempty"`
+	Attached    bool            `json:"attached"`
+	RAMBase     string          `json:"ram_base,omitempty"`
+	GameIDBytes string          `json:"game_id_bytes,omitempty"` // Hex of the header at RAMBase, also when it isn't a game ID
+	GameID      string          `json:"game_id"`
+	ReadMethod  string          `json:"read_method,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Mappings    []MemoryMapping `json:"mappings"`
+	MappingsErr string          `json:"mappings_error,omitempty"`
+}
+
+// sourceKind names the backend the scanner reads from.
+func sourceKind(source MemorySource) string {
+	switch s := source.(type) {
+	case nil:
+		return "none"
+	case *dolphinProcess:
+		return "dolphin"
+	case *MemoryImage:
+		return "dump"
+	case *replaySource:
+		return "replay"
+	case *recordingSource:
+		return sourceKind(s.inner) + " (recording)"
+	}
+	return fmt.Sprintf("%T", source)
+}
+
+// handleDebugHook reports why the tracker does or doesn't hook (GET): the Dolphin processes with their
+// candidate RAM mappings and game header, hook and read statistics and the level names SyncLocation considered.
+func handleDebugHook(d *DolphinHookManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snap := currentSnapshot()
+		resp := struct {
+			Source    string               `json:"source"`
+			Hooked    bool                 `json:"hooked"`
+			GameID    string               `json:"game_id"`
+			Status    string               `json:"game_status"`
+			Pinned    InstancePin          `json:"pinned"`
+			Processes []ProcessDiagnostics `json:"processes"`
+			HookStatsReport
+			Location struct {
+				LevelAddress string `json:"level_address"`
+				LocationScan
+			} `json:"location"`
+		}{
+			Source:          sourceKind(d.Source),
+			Hooked:          snap.IsHooked,
+			GameID:          snap.GameID,
+			Status:          snap.GameStatus,
+			Pinned:          currentPin(),
+			Processes:       make([]ProcessDiagnostics, 0),
+			HookStatsReport: d.stats.report(),
+		}
+		resp.Location.LevelAddress = fmt.Sprintf("0x%08X", snap.LevelAddress)
+		resp.Location.LocationScan = snap.Location
+		if resp.Location.Candidates == nil {
+			resp.Location.Candidates = make([]LocationCandidate, 0)
+		}
+		if resp.Location.Missions == nil {
+			resp.Location.Missions = make([]string, 0)
+		}
+
+		attached := attachedInstance.Load()
+		for _, inst := range listDolphinInstances() {
+			diag := ProcessDiagnostics{PID: inst.PID, Name: inst.Name, Sandbox: inst.Sandbox, Attached: inst.PID == attached,
+				GameID: inst.GameID, ReadMethod: inst.ReadMethod, Error: inst.Error}
+			if inst.RAMBase != 0 {
+				diag.RAMBase = fmt.Sprintf("0x%X", inst.RAMBase)
+				if header, err := readProcessHeader(inst); err == nil {
+					diag.GameIDBytes = fmt.Sprintf("%X", header)
+				}
+			}
+			mappings, err := ramCandidates(inst.PID)
+			diag.Mappings = append(make([]MemoryMapping, 0), mappings...)
+			if err != nil {
+				diag.MappingsErr = err.Error()
+			}
+			resp.Processes = append(resp.Processes, diag)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode diagnostics", http.StatusInternalServerError)
+		}
+	}
+}
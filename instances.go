@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// --- Dolphin Instances ---
+
+// GAME_ID_SIZE is the length of the game ID at the start of main memory ("GMSE01").
+const GAME_ID_SIZE = 6
+
+// DolphinInstance is a running Dolphin process. RAMBase is 0 (and Error set) if no emulated RAM was found in it.
+type DolphinInstance struct {
+	PID     uint32
+	Name    string
+	Sandbox string // SANDBOX_* the process runs in, "" for a plain process
+	RAMBase uintptr
+	GameID  string // "" while no game is running
+	// ReadMethod is how the memory is read (READ_METHOD_* on Linux), "" if it can't be read
+	ReadMethod string
+	Error      string
+}
+
+// Packaging formats Dolphin can run in (detected on Linux)
+const (
+	SANDBOX_FLATPAK  = "flatpak"
+	SANDBOX_SNAP     = "snap"
+	SANDBOX_APPIMAGE = "appimage"
+)
+
+// ProcessMatcher recognizes Dolphin processes by name (glob patterns, case-insensitive) or by their command line (regular expressions).
+type ProcessMatcher struct {
+	names    []string
+	cmdlines []*regexp.Regexp
+}
+
+var dolphinMatcher atomic.Pointer[ProcessMatcher]
+
+// newProcessMatcher compiles the patterns. Empty lists use the defaults of the platform.
+func newProcessMatcher(names, cmdlinePatterns []string) (*ProcessMatcher, error) {
+	if len(names) == 0 {
+		names = defaultDolphinProcessNames
+	}
+	if len(cmdlinePatterns) == 0 {
+		cmdlinePatterns = defaultDolphinCmdlinePatterns
+	}
+	m := &ProcessMatcher{}
+	for _, name := range names {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("process name %q: %w", name, err)
+		}
+		m.names = append(m.names, strings.ToLower(name))
+	}
+	for _, pattern := range cmdlinePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("command line pattern %q: %w", pattern, err)
+		}
+		m.cmdlines = append(m.cmdlines, re)
+	}
+	return m, nil
+}
+
+// currentMatcher returns the matcher set by setMatcher, or the default one.
+func currentMatcher() *ProcessMatcher {
+	if m := dolphinMatcher.Load(); m != nil {
+		return m
+	}
+	m, _ := newProcessMatcher(nil, nil)
+	return m
+}
+
+func setMatcher(m *ProcessMatcher) {
+	dolphinMatcher.Store(m)
+}
+
+// matchName reports whether one of the names (process name, executable, argv[0]) matches a name pattern.
+func (m *ProcessMatcher) matchName(names ...string) bool {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		for _, pattern := range m.names {
+			if ok, _ := path.Match(pattern, strings.ToLower(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String lists the patterns, for messages about processes that weren't found.
+func (m *ProcessMatcher) String() string {
+	patterns := strings.Join(m.names, ", ")
+	for _, re := range m.cmdlines {
+		patterns += fmt.Sprintf(", command line /%s/", re)
+	}
+	return patterns
+}
+
+func (m *ProcessMatcher) matchCmdline(cmdline string) bool {
+	for _, re := range m.cmdlines {
+		if cmdline != "" && re.MatchString(cmdline) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstancePin restricts the scanner to one Dolphin process or to the instance running a certain game.
+type InstancePin struct {
+	PID    uint32 `json:"pid,omitempty"`
+	GameID string `json:"game_id,omitempty"`
+}
+
+var (
+	instancePin      atomic.Pointer[InstancePin]
+	attachedInstance atomic.Uint32 // PID the scanner reads from, 0 if none
+	errPinChanged    = errors.New("the pinned Dolphin instance changed")
+)
+
+func currentPin() InstancePin {
+	if p := instancePin.Load(); p != nil {
+		return *p
+	}
+	return InstancePin{}
+}
+
+func setPin(p InstancePin) {
+	p.GameID = strings.ToUpper(p.GameID)
+	instancePin.Store(&p)
+}
+
+// allows reports whether the pin permits reading from the given process.
+func (p InstancePin) allows(pid uint32, gameID string) bool {
+	return (p.PID == 0 || p.PID == pid) && (p.GameID == "" || strings.EqualFold(p.GameID, gameID))
+}
+
+func (p InstancePin) String() string {
+	switch {
+	case p.PID != 0 && p.GameID != "":
+		return fmt.Sprintf("PID %d with game %s", p.PID, p.GameID)
+	case p.PID != 0:
+		return fmt.Sprintf("PID %d", p.PID)
+	case p.GameID != "":
+		return "game " + p.GameID
+	}
+	return "none"
+}
+
+// parseGameID returns the game ID at the start of main memory, or "" if it doesn't look like one.
+func parseGameID(b []byte) string {
+	if len(b) < GAME_ID_SIZE {
+		return ""
+	}
+	for _, c := range b[:GAME_ID_SIZE] {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return ""
+		}
+	}
+	return string(b[:GAME_ID_SIZE])
+}
+
+// chooseInstance picks the instance the scanner attaches to. Only instances allowed by the pin are considered.
+// Among those the previously attached one wins, so a reconnect stays on the same emulator, then Sunshine, then the lowest PID.
+func chooseInstance(instances []DolphinInstance, pin InstancePin, previous uint32) (DolphinInstance, error) {
+	var candidates []DolphinInstance
+	for _, inst := range instances {
+		if inst.RAMBase != 0 && pin.allows(inst.PID, inst.GameID) {
+			candidates = append(candidates, inst)
+		}
+	}
+	if len(candidates) == 0 {
+		if len(instances) == 0 {
+			return DolphinInstance{}, fmt.Errorf("no Dolphin process found (looking for %s), ensure it is running", currentMatcher())
+		}
+		if pin != (InstancePin{}) {
+			return DolphinInstance{}, fmt.Errorf("no running Dolphin instance matches the pinned %s", pin)
+		}
+		return DolphinInstance{}, fmt.Errorf("no usable Dolphin instance: %s", instances[0].Error)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.PID == previous) != (b.PID == previous) {
+			return a.PID == previous
+		}
+		if sa, sb := strings.HasPrefix(a.GameID, "GMS"), strings.HasPrefix(b.GameID, "GMS"); sa != sb {
+			return sa
+		}
+		return a.PID < b.PID
+	})
+	return candidates[0], nil
+}
+
+// handleInstances lists the running Dolphin instances (GET) or pins one (POST {"pid": 1234} or {"game_id": "GMSE01"},
+// an empty object removes the pin). The pin replaces the one from config.json until the next restart.
+func handleInstances() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var pin InstancePin
+			if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+				http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			setPin(pin)
+			fmt.Printf("Pinned Dolphin instance: %s\n", currentPin())
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		type instanceInfo struct {
+			PID        uint32 `json:"pid"`
+			Name       string `json:"name"`
+			Sandbox    string `json:"sandbox,omitempty"`
+			GameID     string `json:"game_id"`
+			RAMBase    string `json:"ram_base,omitempty"`
+			ReadMethod string `json:"read_method,omitempty"`
+			Error      string `json:"error,omitempty"`
+		}
+		resp := struct {
+			Instances []instanceInfo `json:"instances"`
+			Pinned    InstancePin    `json:"pinned"`
+			Attached  uint32         `json:"attached"` // PID the scanner reads from, 0 if none
+		}{Instances: make([]instanceInfo, 0), Pinned: currentPin(), Attached: attachedInstance.Load()}
+		for _, inst := range listDolphinInstances() {
+			info := instanceInfo{PID: inst.PID, Name: inst.Name, Sandbox: inst.Sandbox, GameID: inst.GameID, ReadMethod: inst.ReadMethod, Error: inst.Error}
+			if inst.RAMBase != 0 {
+				info.RAMBase = fmt.Sprintf("0x%X", inst.RAMBase)
+			}
+			resp.Instances = append(resp.Instances, info)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode instances", http.StatusInternalServerError)
+		}
+	}
+}
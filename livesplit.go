@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- LiveSplit Server Client ---
+
+const LIVESPLIT_DEFAULT_ADDRESS = "localhost:16834" // Default port of the LiveSplit Server component
+
+// Commands of the LiveSplit Server text protocol we send
+const (
+	LIVESPLIT_START = "starttimer"
+	LIVESPLIT_SPLIT = "split"
+	LIVESPLIT_RESET = "reset"
+	LIVESPLIT_PAUSE = "pause"
+)
+
+var livesplitCommands = map[string]bool{
+	LIVESPLIT_START: true,
+	LIVESPLIT_SPLIT: true,
+	LIVESPLIT_RESET: true,
+	LIVESPLIT_PAUSE: true,
+}
+
+// LiveSplitTrigger sends Command to LiveSplit when the embedded trigger fires.
+type LiveSplitTrigger struct {
+	Command string `json:"command"`
+	TimerTrigger
+}
+
+type LiveSplitConfig struct {
+	Enabled  bool               `json:"enabled"`
+	Address  string             `json:"address,omitempty"`
+	Triggers []LiveSplitTrigger `json:"triggers,omitempty"` // Mirrors the run timer when empty
+}
+
+// timerTriggers builds LiveSplit triggers matching the run timer: start, every split and the finish.
+func timerTriggers(cfg TimerConfig) []LiveSplitTrigger {
+	cfg = cfg.withDefaults()
+	triggers := []LiveSplitTrigger{{Command: LIVESPLIT_START, TimerTrigger: cfg.Start}}
+	for _, split := range cfg.Splits {
+		triggers = append(triggers, LiveSplitTrigger{Command: LIVESPLIT_SPLIT, TimerTrigger: split})
+	}
+	return append(triggers, LiveSplitTrigger{Command: LIVESPLIT_SPLIT, TimerTrigger: cfg.Finish})
+}
+
+// LiveSplitClient keeps a connection to the LiveSplit Server and sends commands for matching tracker events.
+type LiveSplitClient struct {
+	address   string
+	triggers  []LiveSplitTrigger
+	commands  chan string
+	connected atomic.Bool
+
+	mu       sync.Mutex
+	fired    map[int]bool // Triggers already sent since the last starttimer/reset
+	finished bool         // Every split was sent, the attempt is over
+}
+
+// NewLiveSplitClient validates the config and creates a client. Call run and follow to start it.
+func NewLiveSplitClient(cfg LiveSplitConfig, timer TimerConfig) (*LiveSplitClient, error) {
+	c := &LiveSplitClient{
+		address:  cfg.Address,
+		triggers: cfg.Triggers,
+		commands: make(chan string, 16),
+		fired:    make(map[int]bool),
+	}
+	if c.address == "" {
+		c.address = LIVESPLIT_DEFAULT_ADDRESS
+	}
+	if len(c.triggers) == 0 {
+		c.triggers = timerTriggers(timer)
+	}
+	for i, trigger := range c.triggers {
+		if !livesplitCommands[trigger.Command] {
+			return nil, fmt.Errorf("livesplit trigger %d: unknown command %q", i, trigger.Command)
+		}
+	}
+	return c, nil
+}
+
+// follow sends the configured commands for the tracker events from now on.
+func (c *LiveSplitClient) follow() {
+	trackerEvents.Listen(c.handleEvent)
+}
+
+func (c *LiveSplitClient) handleEvent(ev TrackerEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, trigger := range c.triggers {
+		if !trigger.matches(ev) {
+			continue
+		}
+		switch {
+		case trigger.Command == LIVESPLIT_RESET:
+			// A new attempt, every other trigger may fire again
+			c.fired, c.finished = make(map[int]bool), false
+		case trigger.Command == LIVESPLIT_START && (c.finished || !c.fired[i]):
+			c.fired, c.finished = make(map[int]bool), false
+		case c.fired[i] || c.finished:
+			// Shine thresholds stay fulfilled and the start level is visited again, so triggers fire only once per attempt
+			continue
+		}
+		c.fired[i] = true
+		c.send(trigger.Command)
+		if trigger.Command == LIVESPLIT_SPLIT && c.allSplitsFired() {
+			// LiveSplit ended the run with the last split, only a start or reset does something now
+			c.finished = true
+		}
+	}
+}
+
+// allSplitsFired reports whether every split trigger was sent in the current attempt. Callers hold c.mu.
+func (c *LiveSplitClient) allSplitsFired() bool {
+	for i, trigger := range c.triggers {
+		if trigger.Command == LIVESPLIT_SPLIT && !c.fired[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// send queues a command. Commands are dropped while disconnected, a split sent late would be wrong anyway.
+func (c *LiveSplitClient) send(command string) {
+	if !c.connected.Load() {
+		fmt.Printf("LiveSplit not connected, dropping %q\n", command)
+		return
+	}
+	select {
+	case c.commands <- command:
+	default:
+		fmt.Printf("LiveSplit queue full, dropping %q\n", command)
+	}
+}
+
+// run connects to LiveSplit and reconnects with a backoff whenever the connection is lost. It never returns.
+func (c *LiveSplitClient) run() {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	for {
+		conn, err := net.DialTimeout("tcp", c.address, 5*time.Second)
+		if err != nil {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		fmt.Printf("Connected to LiveSplit at %s\n", c.address)
+		backoff = time.Second
+
+		c.serve(conn)
+		conn.Close()
+		fmt.Println("Connection lost to LiveSplit, reconnecting...")
+	}
+}
+
+// serve writes queued commands until the connection fails.
+func (c *LiveSplitClient) serve(conn net.Conn) {
+	// LiveSplit only answers queries, so a read returning means the server closed the connection
+	closed := make(chan struct{})
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	c.connected.Store(true)
+	defer c.connected.Store(false)
+
+	for {
+		select {
+		case <-closed:
+			return
+		case command := <-c.commands:
+			conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if _, err := conn.Write([]byte(command + "\r\n")); err != nil {
+				log.Printf("Error sending %q to LiveSplit: %v", command, err)
+				return
+			}
+		}
+	}
+}
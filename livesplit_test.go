@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+// acceptLiveSplit waits for the client to connect to the stand-in server and to be ready to send.
+func acceptLiveSplit(t *testing.T, ln net.Listener, c *LiveSplitClient) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("client didn't connect: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !c.connected.Load(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("client didn't report the connection")
+		}
+	}
+	return conn, bufio.NewReader(conn)
+}
+
+// expectCommands reads the next command lines the client sent.
+func expectCommands(t *testing.T, conn net.Conn, r *bufio.Reader, want ...string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, command := range want {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("waiting for %q: %v", command, err)
+		}
+		if line != command+"\r\n" {
+			t.Fatalf("got %q, want %q", line, command+"\r\n")
+		}
+	}
+	// Nothing else may follow
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if line, err := r.ReadString('\n'); err == nil {
+		t.Fatalf("unexpected command %q", line)
+	}
+}
+
+func TestLiveSplitClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c, err := NewLiveSplitClient(LiveSplitConfig{
+		Address: ln.Addr().String(),
+		Triggers: []LiveSplitTrigger{
+			{Command: LIVESPLIT_START, TimerTrigger: TimerTrigger{Type: TRIGGER_LEVEL, Value: "AIRSTRIP"}},
+			{Command: LIVESPLIT_SPLIT, TimerTrigger: TimerTrigger{Type: TRIGGER_SKILL, Value: "HOVER"}},
+			{Command: LIVESPLIT_SPLIT, TimerTrigger: TimerTrigger{Type: TRIGGER_SHINES, Value: "10"}},
+			{Command: LIVESPLIT_RESET, TimerTrigger: TimerTrigger{Type: TRIGGER_LEVEL, Value: "DELFINO PLAZA"}},
+		},
+	}, TimerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands while disconnected are dropped, not sent once the connection is up
+	c.handleEvent(TrackerEvent{Type: EVENT_SKILL_UNLOCKED, Skill: "HOVER"})
+
+	go c.run()
+	conn, r := acceptLiveSplit(t, ln, c)
+	for _, ev := range []TrackerEvent{
+		{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP"},
+		{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP"}, // Already started
+		{Type: EVENT_SKILL_UNLOCKED, Skill: "HOVER"},
+		{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: 11},
+		{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: 12}, // Threshold already reached
+	} {
+		c.handleEvent(ev)
+	}
+	expectCommands(t, conn, r, LIVESPLIT_START, LIVESPLIT_SPLIT, LIVESPLIT_SPLIT)
+
+	// LiveSplit going away makes the client connect again
+	conn.Close()
+	conn, r = acceptLiveSplit(t, ln, c)
+	defer conn.Close()
+	c.handleEvent(TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "DELFINO PLAZA"})
+	c.handleEvent(TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP"})
+	c.handleEvent(TrackerEvent{Type: EVENT_SKILL_UNLOCKED, Skill: "HOVER"})
+	expectCommands(t, conn, r, LIVESPLIT_RESET, LIVESPLIT_START, LIVESPLIT_SPLIT)
+}
+
+func TestLiveSplitTriggersFollowTheTimer(t *testing.T) {
+	triggers := timerTriggers(TimerConfig{Splits: []TimerTrigger{{Type: TRIGGER_SKILL, Value: "HOVER"}}})
+	want := []struct{ command, value string }{
+		{LIVESPLIT_START, defaultTimerConfig.Start.Value},
+		{LIVESPLIT_SPLIT, "HOVER"},
+		{LIVESPLIT_SPLIT, defaultTimerConfig.Finish.Value},
+	}
+	if len(triggers) != len(want) {
+		t.Fatalf("triggers = %+v", triggers)
+	}
+	for i, w := range want {
+		if triggers[i].Command != w.command || triggers[i].Value != w.value {
+			t.Errorf("trigger %d = %s %s, want %s %s", i, triggers[i].Command, triggers[i].Value, w.command, w.value)
+		}
+	}
+}
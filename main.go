@@ -11,6 +11,12 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -21,6 +27,27 @@ type Config struct {
 	TrackerIntervalSeconds int  `json:"trackerIntervalSeconds"`
 	AutoTrackDefault       bool `json:"autoTrackDefault"`
 	HostInNetwork          bool `json:"hostInNetwork"`
+	// SpoilerEnabled allows /api/spoiler to reveal which shine unlocks which skill (off by default)
+	SpoilerEnabled bool `json:"spoilerEnabled"`
+	// Timer defines when the built-in run timer starts, splits and finishes
+	Timer TimerConfig `json:"timer,omitzero"`
+	// LiveSplit sends start/split commands to a running LiveSplit Server component
+	LiveSplit LiveSplitConfig `json:"livesplit,omitzero"`
+	// GameDataDir holds zones.json, unlocks.json and blue_coin.json files that are merged over the built-in ones
+	GameDataDir string `json:"gameDataDir,omitempty"`
+	// StrictData refuses to load game data with inconsistencies instead of only reporting them
+	StrictData bool `json:"strictData,omitempty"`
+	// AutoMapping decides what happens with entrances and exits observed while playing: "propose" (default), "record" or "off"
+	AutoMapping string `json:"autoMapping,omitempty"`
+	// DolphinPID and DolphinGameID pin the scanner to one of several running Dolphin instances
+	DolphinPID    uint32 `json:"dolphinPid,omitempty"`
+	DolphinGameID string `json:"dolphinGameId,omitempty"`
+	// DolphinProcessNames (glob patterns) and DolphinCmdlinePatterns (regular expressions, Linux only)
+	// replace the built-in ways of recognizing a Dolphin process
+	DolphinProcessNames    []string `json:"dolphinProcessNames,omitempty"`
+	DolphinCmdlinePatterns []string `json:"dolphinCmdlinePatterns,omitempty"`
+	// MemoryDump points to a raw RAM dump that is read instead of a live Dolphin process (for debugging)
+	MemoryDump string `json:"memoryDump,omitempty"`
 }
 
 // --- Embedding ---
@@ -63,14 +90,17 @@ type BlueCoinDefinition struct {
 	MarioPartyLegacyLink string `json:"mariopartylegacylink"`
 }
 
+// SkillMapping tells which shine unlocks a skill. Which fields are filled depends on the requested spoiler level.
 type SkillMapping struct {
-	SkillName     string `json:"skill_name"`
-	HasSkill      bool   `json:"has_skill"`
-	ShineID       uint32 `json:"shine_id"`
-	ShineName     string `json:"shine_name,omitempty"`
-	ZoneName      string `json:"zone_name,omitempty"`
-	IsMapped      bool   `json:"is_mapped"`
-	OriginalIndex int    `json:"-"` // Hidden from JSON, used for sorting
+	SkillName     string  `json:"skill_name"`
+	HasSkill      bool    `json:"has_skill"`
+	ShineID       *uint32 `json:"shine_id,omitempty"` // Only revealed at the "shine" level or when the ID is unmapped
+	WorldName     string  `json:"world_name,omitempty"`
+	ZoneID        string  `json:"zone_id,omitempty"`
+	ZoneName      string  `json:"zone_name,omitempty"`
+	ShineName     string  `json:"shine_name,omitempty"`
+	IsMapped      bool    `json:"is_mapped"`
+	OriginalIndex int     `json:"-"` // Hidden from JSON, used for sorting
 }
 
 // Unlock represents a game capability, item, or nozzle.
@@ -95,17 +125,33 @@ type WorldData struct {
 	Unlocks        []Unlock             `json:"unlocks"`
 	PlazaEntrances []PlazaShines        `json:"plaza_entrances"`
 	BlueCoins      []BlueCoinDefinition `json:"blue_coins"`
+	Stages         []StageDefinition    `json:"stages"`
+	Regions        []RegionDefinition   `json:"regions"`
+
+	shinesByNumID map[int][]string // Built by indexShines, used to translate the shine flags
+	blueCoinFlags map[string]int   // Built by indexBlueCoins, blue coin ID -> flag index
 }
 
 // MemoryState for API Output
 type MemoryState struct {
-	IsHooked       bool            `json:"is_hooked"`
-	CurrentLevel   string          `json:"current_level"`
-	LevelAddress   string          `json:"level_address"`
-	CurrentEpisode string          `json:"current_episode"`
-	EpisodeAddress string          `json:"episode_address"`
-	EpisodeNumber  int             `json:"episode_number"`
-	Unlocks        map[string]bool `json:"unlocks"`
+	Version        uint64 `json:"version"` // Version of the scanner snapshot this state was built from
+	IsHooked       bool   `json:"is_hooked"`
+	GameID         string `json:"game_id"`     // Game ID read from Dolphin, "" if no game runs
+	GameStatus     string `json:"game_status"` // GAME_STATUS_*, whether the tracker can read the game
+	CurrentLevel   string `json:"current_level"`
+	LevelAddress   string `json:"level_address"`
+	CurrentEpisode string `json:"current_episode"`
+	EpisodeAddress string `json:"episode_address"`
+	EpisodeNumber  int    `json:"episode_number"`
+	// Zone from zones.json the player is in, resolved from the stage and episode index ("" if unknown)
+	CurrentZoneID string          `json:"current_zone_id"`
+	StageIndex    int             `json:"stage_index"`
+	EpisodeIndex  int             `json:"episode_index"`
+	Unlocks       map[string]bool `json:"unlocks"`
+	// Shines and blue coins detected from the game's flags, using the IDs from zones.json / blue_coin.json
+	CollectedShines    []string `json:"collected_shines"`
+	CollectedBlueCoins []string `json:"collected_blue_coins"`
+	ShineTotal         int      `json:"shine_total"`
 	// Configvalues for Memory State
 	Interval  int    `json:"interval"`
 	AutoTrack bool   `json:"auto_track"`
@@ -114,15 +160,24 @@ type MemoryState struct {
 
 // --- Dolphin Hook Logic ---
 
+// The addresses of the game state depend on the region, see regions.json.
 const (
 	PROCESS_VM_READ           = 0x0010
 	PROCESS_QUERY_INFORMATION = 0x0400
-	ADDR_SKILLS               = 0x804496AF
-	ADDR_SHINES               = ADDR_SKILLS + 0x19 // We will use this maybe at some point to show which shine will unlock a skill
-	ADDR_SHINES_TOTAL         = 0x8043A5A4         // This address holds the total number of shines collected, which can be useful for certain unlock conditions and tracking overall progress.
-	ADDR_SEED                 = 0x80449698
+	FLAG_BYTES                = 0x77 // Size of the boolean flag bitfield
 )
 
+// Location detection
+const (
+	LOCATION_SCAN_BLOCK      = 0x400000        // Bytes per read while scanning the whole memory for a level name
+	LEVEL_CONTEXT_SIZE       = 1024            // Bytes before the level name that are searched for the mission title
+	LOCATION_RESCAN_INTERVAL = 5 * time.Second // Minimum time between searches while the stage doesn't change
+	LOCATION_TITLE_SEARCHES  = 3               // Searches for the mission title per visit of a stage from stages.json
+)
+
+// SEED_CONFIRM_POLLS is how many scanner passes in a row must read the same seed before it is used.
+const SEED_CONFIRM_POLLS = 2
+
 // Possible Levelnames the Hook can find
 var levels = []string{
 	"BIANCO HILLS", "RICCO HARBOR", "GELATO BEACH", "PINNA PARK",
@@ -130,6 +185,14 @@ var levels = []string{
 	"DELFINO PLAZA", "AIRSTRIP",
 }
 
+var longestLevelName = func() int {
+	n := 0
+	for _, name := range levels {
+		n = max(n, len(name))
+	}
+	return n
+}()
+
 // Skill names the hook can give to the tracker
 var skillNames = []string{
 	"DOUBLE_JUMP", "TRIPLE_JUMP", "SIDEFLIP", "GRAB", "GROUND_SPIN",
@@ -139,25 +202,110 @@ var skillNames = []string{
 	"ROCKET", "TURBO", "CLIMBING",
 }
 
-// DolphinHookManager manages the connection and memory reading from the Dolphin emulator.
+// DolphinHookManager manages the connection to a MemorySource and the state read from it.
 type DolphinHookManager struct {
-	PID            uint32
-	Handle         uintptr
-	BaseAddr       uintptr
+	Source         MemorySource
 	IsHooked       bool
+	GameID         string
+	GameStatus     string
+	Region         *RegionDefinition // Address table of the running game, nil if it can't be read
 	CurrentLevel   string
 	CurrentEpisode string
 	LevelAddress   uint32
 	EpisodeAddress uint32
 	EpisodeNumber  int
+	StageIndex     int
+	EpisodeIndex   int
+	CurrentZoneID  string
 	LastSkills     []byte
 	ShineIDs       []uint32
-	Seed           string
+	Flags          []byte
+	TotalShines    int
+	Seed           string // Only set once confirmed, see confirmSeed
+
+	seedCandidate string // Seed read by the last passes, waiting for confirmation
+	seedPolls     int
+
+	// Location cache of SyncLocation
+	locationStage    int // Stage and episode index LevelAddress belongs to
+	locationEpisode  int
+	locationSearches int // Searches since the stage changed
+	lastLocationScan time.Time
+	Location         LocationScan // Candidates of the last full scan, for /api/debug/hook
+
+	stats HookStats // Safe to read from other goroutines
 }
 
-// SyncLocation scans the game's memory to determine the current level and episode.
+// SyncLocation determines the current level and episode. In the stages of stages.json the level comes from the
+// stage index read by SyncZone. The mission title has no fixed address, it is the text in front of the level name.
+// After a stage change memory is searched for that level name, a few times at most since the text can show up a
+// moment after the stage, and from then on only the address found is checked. All level names are searched for
+// only while the stage index is invalid or missing in stages.json (e.g. on the title screen).
 func (d *DolphinHookManager) SyncLocation() {
-	blockSize := 0x400000
+	level := currentWorld().levelForStage(d.StageIndex)
+	names := levels
+	if level != "" {
+		names = []string{level}
+	}
+
+	if d.locationStage != d.StageIndex || d.locationEpisode != d.EpisodeIndex {
+		d.locationStage, d.locationEpisode, d.locationSearches = d.StageIndex, d.EpisodeIndex, 0
+		d.lastLocationScan = time.Time{}
+		// The text at the old address may still be the title of the previous episode
+		d.LevelAddress = 0
+		if level != "" {
+			d.CurrentLevel, d.CurrentEpisode, d.EpisodeAddress = level, "???", 0
+		}
+	} else if d.LevelAddress != 0 && d.readLevelAt(d.LevelAddress, names) {
+		return
+	}
+
+	if level != "" && d.locationSearches >= LOCATION_TITLE_SEARCHES {
+		return
+	}
+	if time.Since(d.lastLocationScan) < LOCATION_RESCAN_INTERVAL {
+		return
+	}
+	d.lastLocationScan = time.Now()
+	d.locationSearches++
+	d.scanLocation(names)
+}
+
+// readLevelAt checks whether one of the level names still starts at addr and reads the mission title before it.
+func (d *DolphinHookManager) readLevelAt(addr uint32, names []string) bool {
+	contextStart := uint32(GC_RAM_BASE)
+	if addr-GC_RAM_BASE > LEVEL_CONTEXT_SIZE {
+		contextStart = addr - LEVEL_CONTEXT_SIZE
+	}
+	rel := int(addr - contextStart)
+	data, err := d.Read(contextStart, rel+longestLevelName)
+	if err != nil || data == nil {
+		return false
+	}
+	if rel > 0 && data[rel-1] != 0x00 {
+		return false
+	}
+	for _, name := range names {
+		if bytes.HasPrefix(data[rel:], []byte(name)) {
+			d.CurrentLevel = name
+			d.CurrentEpisode, d.EpisodeAddress = findMostLikelyMission(data[:rel])
+			return true
+		}
+	}
+	return false
+}
+
+// scanLocation searches the game's memory for one of the level names and the mission title before it.
+func (d *DolphinHookManager) scanLocation(names []string) {
+	scan := LocationScan{ScannedAt: time.Now()}
+	defer func() { d.Location = scan }()
+	consider := func(name string, addr uint32, reason string) {
+		if len(scan.Candidates) < LOCATION_MAX_CANDIDATES {
+			scan.Candidates = append(scan.Candidates, LocationCandidate{Level: name, Address: fmt.Sprintf("0x%08X", addr), Accepted: reason == "", Reason: reason})
+		}
+	}
+
+	blockSize := LOCATION_SCAN_BLOCK
 	// Scan up to 0x81800000
 	for i := 0; i < 6; i++ {
 		offset := uint32(i) * uint32(blockSize)
@@ -166,7 +314,7 @@ func (d *DolphinHookManager) SyncLocation() {
 			continue
 		}
 
-		for _, name := range levels {
+		for _, name := range names {
 			// We look for all occurrences in the block, not just the first one
 			idx := 0
 			for {
@@ -179,22 +327,26 @@ func (d *DolphinHookManager) SyncLocation() {
 				absAddr := 0x80000000 + offset + uint32(actualIdx)
 
 				if absAddr >= 0x80960000 && absAddr <= 0x80970000 {
+					consider(name, absAddr, "inside 0x80960000-0x80970000")
 					idx = actualIdx + 1
 					continue
 				}
 
 				if actualIdx > 0 && data[actualIdx-1] != 0x00 {
+					consider(name, absAddr, "not preceded by a zero byte")
 					idx = actualIdx + 1
 					continue
 				}
 
 				// If we passed the filters, this is likely our real active level
+				consider(name, absAddr, "")
 				d.CurrentLevel = name
 				d.LevelAddress = absAddr
 
 				// Grab the mission context
-				contextStart := max(0, actualIdx-1024)
+				contextStart := max(0, actualIdx-LEVEL_CONTEXT_SIZE)
 				d.CurrentEpisode, d.EpisodeAddress = findMostLikelyMission(data[contextStart:actualIdx])
+				scan.Missions = missionCandidates(data[contextStart:actualIdx], 5)
 
 				return
 			}
@@ -203,7 +355,7 @@ func (d *DolphinHookManager) SyncLocation() {
 }
 
 func (d *DolphinHookManager) GetTotalShines() int {
-	data, err := d.Read(ADDR_SHINES_TOTAL, 4)
+	data, err := d.Read(uint32(d.Region.Addresses.ShinesTotal), 4)
 	if err != nil || data == nil {
 		return 0
 	}
@@ -213,16 +365,33 @@ func (d *DolphinHookManager) GetTotalShines() int {
 // --- Global State ---
 
 var (
-	currentWorld WorldData
-	dm           = &DolphinHookManager{CurrentLevel: "SEARCHING..."}
-	globalCfg    Config
+	loadedWorld atomic.Pointer[WorldData]
+	dm          = &DolphinHookManager{CurrentLevel: "SEARCHING...", StageIndex: NO_STAGE, EpisodeIndex: NO_STAGE}
 )
 
+// currentWorld returns the game data in use. It is replaced as a whole on reload and must not be modified.
+func currentWorld() *WorldData {
+	return loadedWorld.Load()
+}
+
+func setWorld(w WorldData) {
+	loadedWorld.Store(&w)
+}
+
 // --- Helper Functions ---
 
 func findMostLikelyMission(data []byte) (string, uint32) {
+	if titles := missionCandidates(data, 1); len(titles) > 0 {
+		return titles[0], binary.BigEndian.Uint32(data)
+	}
+	return "???", 0
+}
+
+// missionCandidates returns up to limit strings before a level name that could be the mission title, the closest first.
+func missionCandidates(data []byte, limit int) []string {
+	var titles []string
 	parts := bytes.Split(data, []byte{0x00})
-	for i := len(parts) - 1; i >= 0; i-- {
+	for i := len(parts) - 1; i >= 0 && len(titles) < limit; i-- {
 		p := bytes.TrimSpace(parts[i])
 		if len(p) > 4 && len(p) < 40 {
 			isASCII := true
@@ -233,11 +402,11 @@ func findMostLikelyMission(data []byte) (string, uint32) {
 				}
 			}
 			if isASCII {
-				return string(p), binary.BigEndian.Uint32(data)
+				titles = append(titles, string(p))
 			}
 		}
 	}
-	return "???", 0
+	return titles
 }
 
 func max(a, b int) int {
@@ -249,13 +418,36 @@ func max(a, b int) int {
 
 // --- Main Logic ---
 
-// loadGameData parses JSON files and constructs the initial world state.
-// This should only be called once during startup.
-func loadGameData() {
+// loadGameData parses the JSON files (embedded, merged with overlayDir if set) and constructs the initial world state.
+// Inconsistencies in the files are reported, in strict mode they stop the tracker.
+// This should only be called once during startup, later changes go through /api/data/reload.
+func loadGameData(overlayDir string, strict bool) {
+	if overlayDir != "" {
+		fmt.Printf("Using game data overrides from %s\n", overlayDir)
+	}
+	world, err := parseGameData(overlayGameData(overlayDir))
+	if err != nil {
+		log.Fatalf("Error loading game data: %v", err)
+	}
+	problems := validateWorld(&world)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if err := checkStrict(problems); strict && err != nil {
+		log.Fatalf("Not starting in strict mode: %v", err)
+	}
+	setWorld(world)
+
+	fmt.Printf("Data loaded successfully: %d zones, %d entrances configured, %d unlocks, %d blue coins.\n",
+		len(world.Zones), len(world.PlazaEntrances), len(world.Unlocks), len(world.BlueCoins))
+}
+
+// parseGameData builds the world from the zones.json, unlocks.json, blue_coin.json and stages.json files.
+func parseGameData(read gameDataReader) (WorldData, error) {
 	// A. Load Zones
-	zoneFile, err := dataEmbed.ReadFile("data/zones.json")
+	zoneFile, err := read("zones.json")
 	if err != nil {
-		log.Fatalf("Error reading embedded zones.json: %v", err)
+		return WorldData{}, fmt.Errorf("reading zones.json: %w", err)
 	}
 
 	// Temporary wrapper to match the JSON structure structure
@@ -264,7 +456,7 @@ func loadGameData() {
 	}
 
 	if err := json.Unmarshal(zoneFile, &zoneWrapper); err != nil {
-		log.Fatalf("Error parsing zones.json: %v", err)
+		return WorldData{}, fmt.Errorf("parsing zones.json: %w", err)
 	}
 
 	// The JSON uses the ID as the map key. We inject that key into the struct itself
@@ -275,16 +467,16 @@ func loadGameData() {
 	}
 
 	// B. Load Unlocks
-	unlockFile, err := dataEmbed.ReadFile("data/unlocks.json")
+	unlockFile, err := read("unlocks.json")
 	if err != nil {
-		log.Fatalf("Error reading embedded unlocks.json: %v", err)
+		return WorldData{}, fmt.Errorf("reading unlocks.json: %w", err)
 	}
 
 	var unlockWrapper struct {
 		Unlocks []Unlock `json:"unlocks"`
 	}
 	if err := json.Unmarshal(unlockFile, &unlockWrapper); err != nil {
-		log.Fatalf("Error parsing unlocks.json: %v", err)
+		return WorldData{}, fmt.Errorf("parsing unlocks.json: %w", err)
 	}
 
 	// C. Define Plaza Entrances programmatically
@@ -333,7 +525,7 @@ func loadGameData() {
 	}
 
 	// D. Load Blue Coins
-	bcFile, err := dataEmbed.ReadFile("data/blue_coin.json")
+	bcFile, err := read("blue_coin.json")
 	if err != nil {
 		log.Printf("Warning: Could not find blue_coin.json: %v", err)
 	}
@@ -345,45 +537,71 @@ func loadGameData() {
 		}
 	}
 
-	// Assign compiled data to global state
-	currentWorld = WorldData{
+	// E. Load the stage table used to resolve the current zone
+	stages, err := parseStages(read)
+	if err != nil {
+		return WorldData{}, err
+	}
+
+	// F. Load the memory addresses of each release
+	regions, err := parseRegions(read)
+	if err != nil {
+		return WorldData{}, err
+	}
+
+	world := WorldData{
 		Zones:          zoneWrapper.Zones,
 		Unlocks:        unlockWrapper.Unlocks,
 		PlazaEntrances: entrances,
 		BlueCoins:      blueCoins,
+		Stages:         stages,
+		Regions:        regions,
 	}
-
-	fmt.Printf("Data loaded successfully: %d zones, %d entrances configured, %d unlocks, %d blue coins.\n",
-		len(currentWorld.Zones), len(currentWorld.PlazaEntrances), len(currentWorld.Unlocks), len(currentWorld.BlueCoins))
+	world.indexShines()
+	world.indexBlueCoins()
+	return world, nil
 }
 
 // --- Server API ---
 
-func LoadConfig() Config {
-	defaultConfig := Config{Port: 8080, TrackerIntervalSeconds: 5, AutoTrackDefault: true, HostInNetwork: false}
-	file, err := os.ReadFile("config.json")
+var defaultConfig = Config{Port: 8080, TrackerIntervalSeconds: 5, AutoTrackDefault: true, HostInNetwork: false}
+
+// LoadConfig reads the config file, creating it with the defaults if it doesn't exist.
+// Settings missing in the file keep their default. Invalid values stop the tracker.
+func LoadConfig(path string) Config {
+	file, err := os.ReadFile(path)
 	if err != nil {
 		// If file doesn't exist, write the file then load defaults
 		if os.IsNotExist(err) {
 			configData, _ := json.MarshalIndent(defaultConfig, "", "  ")
-			writeErr := os.WriteFile("config.json", configData, 0644)
+			writeErr := os.WriteFile(path, configData, 0644)
 			if writeErr != nil {
-				log.Fatalf("Error creating default config.json: %v", writeErr)
+				log.Fatalf("Error creating default %s: %v", path, writeErr)
 			} else {
-				fmt.Println("Created default config.json")
+				fmt.Printf("Created default %s\n", path)
 			}
 		} else {
-			log.Fatalf("Error reading config.json: %v", err)
+			log.Fatalf("Error reading %s: %v", path, err)
 		}
 		return defaultConfig
 	}
-	var loadedConfig Config
-	if err := json.Unmarshal(file, &loadedConfig); err != nil {
-		fmt.Printf("Warning: Failed to parse config.json, using default port %d\n", defaultConfig.Port)
-		return defaultConfig
+	loadedConfig, problems := parseConfig(file, defaultConfig)
+	for _, p := range problems {
+		fmt.Printf("%s: %s\n", path, p)
+	}
+	if hasErrors(problems) {
+		log.Fatalf("Invalid %s, please fix the problems above.", path)
 	}
 	return loadedConfig
 }
+
+// dataDir holds everything the tracker writes: saves, history, runs and by default the config.
+var dataDir = "."
+
+// dataPath returns the location of name inside the data directory.
+func dataPath(name string) string {
+	return filepath.Join(dataDir, name)
+}
 func runMemoryScanner() {
 	for {
 		if !dm.IsHooked {
@@ -393,91 +611,167 @@ func runMemoryScanner() {
 			}
 			fmt.Println("Successfully hooked to Dolphin!")
 		}
-		/*
-			oldSkills := dm.LastSkills
-			oldLocation := dm.CurrentLevel
-			oldEpisode := dm.CurrentEpisode
+		time.Sleep(scanOnce())
+	}
+}
 
-		*/
+// scanOnce reads the game state once and publishes what changed. It returns how long to wait before the next pass.
+func scanOnce() time.Duration {
+	if err := dm.SyncGame(); err != nil {
+		dropConnection()
+		return 0
+	}
+	if dm.Region == nil {
+		// Another game or a region without addresses, reading it would only give garbage
+		publishScan()
+		return 2 * time.Second
+	}
+	addrs := dm.Region.Addresses
+
+	// The zone goes first, a stage change tells SyncLocation to look for the level name again
+	dm.SyncZone()
+	dm.SyncLocation()
+	s, err := dm.Read(uint32(addrs.Skills), 23)
+	if err != nil || s == nil {
+		dropConnection()
+		return 0
+	}
 
-		dm.SyncLocation()
-		s, err := dm.Read(ADDR_SKILLS, 23)
-		if err != nil || s == nil {
-			fmt.Println("Connection lost to Dolphin, cleaning up...")
+	// Read the shines that are linked to the skills, this can be used to show which shine will unlock a skill in the UI
+	shineData, err := dm.Read(uint32(addrs.Shines), 23*4)
+	if err == nil && shineData != nil {
+		dm.ShineIDs = make([]uint32, 0) // Reset the slice
+		// Convert that to a usable format (4 values per skill, total 23 skills)
+		for i := 0; i < 23; i++ {
+			shineID := binary.BigEndian.Uint32(shineData[i*4 : (i+1)*4])
+			dm.ShineIDs = append(dm.ShineIDs, shineID)
+		}
+	}
 
-			dm.Close()
+	dm.LastSkills = s
 
-			dm.IsHooked = false
-			time.Sleep(1 * time.Second)
-			continue
-		}
+	// The flag bitfield tells us which shines and blue coins have been collected
+	flags, err := dm.Read(uint32(addrs.Flags), FLAG_BYTES)
+	if err == nil && flags != nil {
+		dm.Flags = flags
+	}
+	dm.TotalShines = dm.GetTotalShines()
 
-		// Read the shines that are linked to the skills, this can be used to show which shine will unlock a skill in the UI
-		shineData, err := dm.Read(ADDR_SHINES, 23*4)
-		if err == nil && shineData != nil {
-			dm.ShineIDs = make([]uint32, 0) // Reset the slice
-			// Convert that to a usable format (4 values per skill, total 23 skills)
-			for i := 0; i < 23; i++ {
-				shineID := binary.BigEndian.Uint32(shineData[i*4 : (i+1)*4])
-				dm.ShineIDs = append(dm.ShineIDs, shineID)
-			}
+	seed, err := dm.ReadSeed()
+	if err != nil {
+		fmt.Println("Failed to read seed:", err)
+	} else {
+		dm.confirmSeed(seed)
+	}
+
+	// Hand a consistent copy of this pass to the HTTP handlers
+	publishScan()
+
+	// Wait before next scan
+	return 500 * time.Millisecond
+}
+
+// dropConnection unhooks from a source that can't be read anymore.
+func dropConnection() {
+	fmt.Println("Connection lost to Dolphin, cleaning up...")
+
+	dm.Close()
+
+	dm.IsHooked = false
+	publishScan()
+	time.Sleep(1 * time.Second)
+}
+
+// publishScan makes the scanner state visible to the handlers and emits events for everything that changed.
+func publishScan() {
+	snap := dm.Snapshot()
+	trackerEvents.publishChanges(publishSnapshot(snap), snap)
+}
+
+func main() {
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// serve runs the tracker: the memory scanner and the web interface. It only returns on fatal errors.
+func serve(opts ServeOptions) {
+	if opts.DataDir != "" {
+		if err := os.MkdirAll(opts.DataDir, 0755); err != nil {
+			log.Fatalf("Error creating data directory: %v", err)
 		}
+		dataDir = opts.DataDir
+	}
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = dataPath("config.json")
+	}
 
-		dm.LastSkills = s
-		// Commented out because its code for shineID Mapping i use for myself
-		/*
-			if oldSkills != nil {
-				if bytes.Equal(oldSkills, dm.LastSkills) == false {
-					// Log the difference (which skill we got basically) and the location we are in/where in
-					for i := 0; i < len(s); i++ {
-						if i < len(oldSkills) && oldSkills[i] != s[i] {
-							fmt.Printf("Skill %s changed from %d to %d\n", skillNames[i], oldSkills[i], s[i])
-							var foundShine bool
-							for j, zone := range currentWorld.Zones {
-								for _, shine := range zone.ShinesAvailable {
-									if shine.NumID == int(dm.ShineIDs[i]) {
-										fmt.Println("This is shineID", dm.ShineIDs[i], "which is linked to this skill, and total shines collected:", dm.GetTotalShines())
-										fmt.Printf("This shine is located in zone %s (%s) and is called %s\n", j, zone.Name, shine.Name)
-										foundShine = true
-										break
-									}
-								}
-							}
-							if !foundShine && string(s[i]) != "" {
-								msg := fmt.Sprintf("ID: %d | Level: %s | Ep: %s - ", dm.ShineIDs[i], dm.CurrentLevel, dm.CurrentEpisode)
-								msg += fmt.Sprintf("Old Location: %s | Old Episode: %s", oldLocation, oldEpisode)
-								fmt.Println("\n[!!!] MISSING MAPPING [!!!]")
-								fmt.Println("This is shineID", dm.ShineIDs[i], "which is linked to skill "+skillNames[i]+", and total shines collected:", dm.GetTotalShines())
-								fmt.Println(msg)
-
-								// Play sound (ASCII Bell)
-								fmt.Print("\a")
-
-								// Show Windows Popup
-								go ShowPopup("Missing Shine Mapping", "Found unmapped ID! Check console for details.")
-							}
-						}
-					}
-				}
-			}
+	cfg := LoadConfig(opts.ConfigPath)
+	opts.applyTo(&cfg)
+	setConfig(cfg)
+	go watchConfig(opts.ConfigPath, opts)
 
-		*/
+	loadGameData(cfg.GameDataDir, cfg.StrictData)
+	setPin(InstancePin{PID: cfg.DolphinPID, GameID: cfg.DolphinGameID})
+	matcher, _ := newProcessMatcher(cfg.DolphinProcessNames, cfg.DolphinCmdlinePatterns) // Checked by LoadConfig
+	setMatcher(matcher)
+	dm.Source = newMemorySource(cfg)
 
-		dm.Seed, err = dm.ReadSeed()
+	if opts.Replay != "" {
+		replay, err := OpenReplay(opts.Replay, opts.Speed)
+		if err != nil {
+			log.Fatalf("Error opening replay: %v", err)
+		}
+		dm.Source = replay
+	}
+	if opts.Record != "" {
+		recorder, err := NewRecordingSource(dm.Source, opts.Record)
 		if err != nil {
-			fmt.Println("Failed to read seed:", err)
+			log.Fatalf("Error creating recording: %v", err)
 		}
+		dm.Source = recorder
+		fmt.Printf("Recording memory reads to %s\n", opts.Record)
+
+		// Complete the recording file when the tracker is stopped
+		go func() {
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+			<-stop
+			if err := recorder.Finish(); err != nil {
+				log.Printf("Error finishing recording: %v", err)
+			}
+			os.Exit(0)
+		}()
+	}
 
-		// Wait before next scan
-		time.Sleep(500 * time.Millisecond)
+	slots, err := OpenSlotManager(dataPath(SAVES_DIR))
+	if err != nil {
+		log.Fatalf("Error loading save slots: %v", err)
 	}
-}
+	slots.followSeed()
 
-func main() {
-	globalCfg = LoadConfig()
-	loadGameData()
+	history := NewHistoryLog(dataPath(HISTORY_DIR))
+	history.follow()
+
+	mappings := NewMappingObserver(slots)
+	mappings.follow()
 
-	go runMemoryScanner()
+	timer := NewRunTimer(dataPath(RUNS_DIR), cfg.Timer)
+	timer.follow()
+
+	if cfg.LiveSplit.Enabled {
+		livesplit, err := NewLiveSplitClient(cfg.LiveSplit, cfg.Timer)
+		if err != nil {
+			log.Fatalf("Invalid livesplit config: %v", err)
+		}
+		livesplit.follow()
+		go livesplit.run()
+	}
+
+	if opts.NoHook {
+		fmt.Println("Memory hooking is disabled, track your progress manually.")
+		publishSnapshot(&ScannerSnapshot{CurrentLevel: "HOOK DISABLED"})
+	} else {
+		go runMemoryScanner()
+	}
 
 	publicFiles, err := fs.Sub(staticEmbed, "static")
 	if err != nil {
@@ -488,119 +782,88 @@ func main() {
 
 	http.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		err = json.NewEncoder(w).Encode(currentWorld)
+		err := json.NewEncoder(w).Encode(currentWorld())
 		if err != nil {
 			http.Error(w, "Failed to encode data", http.StatusInternalServerError)
 		}
 	})
 
+	http.HandleFunc("/api/data/reload", handleDataReload(cfg.GameDataDir, cfg.StrictData))
+
 	http.HandleFunc("/api/memory", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
+		snap := currentSnapshot()
 		unlockMap := make(map[string]bool)
-		if dm.LastSkills != nil {
-			for i, name := range skillNames {
-				if i < len(dm.LastSkills) {
-					unlockMap[name] = dm.LastSkills[i] != 0
-				}
+		for i, name := range skillNames {
+			if i < len(snap.Skills) {
+				unlockMap[name] = snap.HasSkill(i)
 			}
 		}
 
 		state := MemoryState{
-			IsHooked:       dm.IsHooked,
-			CurrentLevel:   dm.CurrentLevel,
-			LevelAddress:   fmt.Sprintf("0x%08X", dm.LevelAddress),
-			CurrentEpisode: dm.CurrentEpisode,
-			EpisodeAddress: fmt.Sprintf("0x%08X", dm.EpisodeAddress),
-			EpisodeNumber:  dm.EpisodeNumber,
-			Unlocks:        unlockMap,
-			Interval:       globalCfg.TrackerIntervalSeconds,
-			AutoTrack:      globalCfg.AutoTrackDefault,
-			Seed:           dm.Seed,
+			Version:            snap.Version,
+			IsHooked:           snap.IsHooked,
+			GameID:             snap.GameID,
+			GameStatus:         snap.GameStatus,
+			CurrentLevel:       snap.CurrentLevel,
+			LevelAddress:       fmt.Sprintf("0x%08X", snap.LevelAddress),
+			CurrentEpisode:     snap.CurrentEpisode,
+			EpisodeAddress:     fmt.Sprintf("0x%08X", snap.EpisodeAddress),
+			EpisodeNumber:      snap.EpisodeNumber,
+			CurrentZoneID:      snap.CurrentZoneID,
+			StageIndex:         snap.StageIndex,
+			EpisodeIndex:       snap.EpisodeIndex,
+			Unlocks:            unlockMap,
+			CollectedShines:    currentWorld().collectedShines(snap.Flags),
+			CollectedBlueCoins: currentWorld().collectedBlueCoins(snap.Flags),
+			ShineTotal:         snap.TotalShines,
+			Interval:           currentConfig().TrackerIntervalSeconds,
+			AutoTrack:          currentConfig().AutoTrackDefault,
+			Seed:               snap.Seed,
 		}
-		err = json.NewEncoder(w).Encode(state)
+		err := json.NewEncoder(w).Encode(state)
 		if err != nil {
 			http.Error(w, "Failed to encode memory state", http.StatusInternalServerError)
 		}
 	})
 
-	// This endpoint is currently used for testing. in the future it should be used for a spoiler UI that tells you which shine will give you a ability
-	/*
-		// New Endpoint: Skill and Shine Mapping
-		http.HandleFunc("/api/spoiler", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-
-			if dm.LastSkills == nil || dm.ShineIDs == nil {
-				http.Error(w, "Memory data not yet available", http.StatusServiceUnavailable)
-				return
-			}
-
-			mappings := make([]SkillMapping, 0)
-
-			for i, skill := range skillNames {
-				if i >= len(dm.LastSkills) || i >= len(dm.ShineIDs) {
-					break
-				}
-
-				baseMapping := SkillMapping{
-					SkillName:     skill,
-					HasSkill:      dm.LastSkills[i] != 0,
-					ShineID:       dm.ShineIDs[i],
-					IsMapped:      false,
-					OriginalIndex: i, // Store the index from skillNames
-				}
-
-				foundAtLeastOne := false
-				for _, zone := range currentWorld.Zones {
-					for _, shine := range zone.ShinesAvailable {
-						if shine.NumID == int(baseMapping.ShineID) {
-							m := baseMapping
-							m.ShineName = shine.Name
-							m.ZoneName = zone.Name
-							m.IsMapped = true
-							mappings = append(mappings, m)
-							foundAtLeastOne = true
-						}
-					}
-				}
-
-				if !foundAtLeastOne {
-					mappings = append(mappings, baseMapping)
-				}
-			}
-
-			// Sort: Primary by OriginalIndex, Secondary by ZoneName
-			sort.Slice(mappings, func(i, j int) bool {
-				if mappings[i].OriginalIndex != mappings[j].OriginalIndex {
-					return mappings[i].OriginalIndex < mappings[j].OriginalIndex
-				}
-				return mappings[i].ZoneName < mappings[j].ZoneName
-			})
-
-			if err := json.NewEncoder(w).Encode(mappings); err != nil {
-				http.Error(w, "Failed to encode skill mapping", http.StatusInternalServerError)
-			}
-		})
-
-	*/
-
-	addr := fmt.Sprintf("localhost:%d", globalCfg.Port)
-	addrStr := []string{"localhost"}
-	if globalCfg.HostInNetwork {
-
-		addr = fmt.Sprintf("0.0.0.0:%d", globalCfg.Port)
-		localIPs := getLocalIPs()
-		addrStr = append(addrStr, localIPs...)
-
+	http.HandleFunc("/api/events", handleEvents)
+	http.HandleFunc("/api/state", handleState(slots))
+	http.HandleFunc("/api/slots", handleSlots(slots))
+
+	http.HandleFunc("/api/spoiler", handleSpoiler)
+	http.HandleFunc("/api/spoiler-log", handleSpoilerLog(slots))
+	http.HandleFunc("/api/mappings", handleMappings(mappings, slots))
+	http.HandleFunc("/api/mappings/confirm", handleMappingAction(mappings, true))
+	http.HandleFunc("/api/mappings/dismiss", handleMappingAction(mappings, false))
+	http.HandleFunc("/api/instances", handleInstances())
+	http.HandleFunc("/api/debug/hook", handleDebugHook(dm))
+	http.HandleFunc("/api/history", handleHistory(history))
+	http.HandleFunc("/api/timer", handleTimer(timer))
+	http.HandleFunc("/api/timer/reset", handleTimerReset(timer))
+	http.HandleFunc("/api/timer/export", handleTimerExport(timer))
+
+	// An explicit --bind is used as given, hostInNetwork only decides the default
+	host, listenAll := "localhost", cfg.HostInNetwork
+	if opts.Bind != "" {
+		host = opts.Bind
+		ip := net.ParseIP(opts.Bind)
+		listenAll = ip != nil && ip.IsUnspecified()
+	} else if cfg.HostInNetwork {
+		host = "0.0.0.0"
 	}
+	addrStr := []string{host}
+	if listenAll {
+		addrStr = append([]string{"localhost"}, getLocalIPs()...)
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(cfg.Port))
 	fmt.Println("Starting server... Web interface available at:")
 
 	for i, a := range addrStr {
 		if i == 1 {
-			if globalCfg.HostInNetwork {
-				fmt.Println(" - Listening on all interfaces. - UI is also available in local network at:")
-			}
+			fmt.Println(" - Listening on all interfaces. - UI is also available in local network at:")
 		}
-		fmt.Printf(" - http://%s:%d\n", a, globalCfg.Port)
+		fmt.Printf(" - http://%s\n", net.JoinHostPort(a, strconv.Itoa(cfg.Port)))
 
 	}
 	fmt.Printf("Open your web browser and navigate to the above URL to access the tracker interface.\n")
@@ -627,27 +890,29 @@ func getLocalIPs() []string {
 	return ips
 }
 
-/*
-func ShowPopup(title, text string) {
-	var mod = syscall.NewLazyDLL("user32.dll")
-	var proc = mod.NewProc("MessageBoxW")
-
-	// Convert strings to UTF-16 for Windows
-	t, _ := syscall.UTF16PtrFromString(title)
-	m, _ := syscall.UTF16PtrFromString(text)
-
-	// 0x00000030 = Icon Warning | 0x00000000 = OK button
-	proc.Call(0, uintptr(unsafe.Pointer(m)), uintptr(unsafe.Pointer(t)), 0x30)
-}
-*/
-
 func (d *DolphinHookManager) ReadSeed() (string, error) {
 	if !d.IsHooked {
 		return "", fmt.Errorf("not hooked")
 	}
-	data, err := d.Read(ADDR_SEED, 4)
+	data, err := d.Read(uint32(d.Region.Addresses.Seed), 4)
 	if err != nil || data == nil {
 		return "", err
 	}
 	return fmt.Sprintf("%X", data), nil
 }
+
+// confirmSeed makes a seed read from memory the current one once it was read SEED_CONFIRM_POLLS times in a row.
+// While the game boots the seed address holds zeros or leftovers, which must not switch the save slot.
+func (d *DolphinHookManager) confirmSeed(seed string) {
+	if strings.Trim(seed, "0") == "" {
+		d.seedCandidate, d.seedPolls = "", 0
+		return
+	}
+	if seed != d.seedCandidate {
+		d.seedCandidate, d.seedPolls = seed, 0
+	}
+	d.seedPolls++
+	if d.seedPolls >= SEED_CONFIRM_POLLS {
+		d.Seed = seed
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Automatic Entrance Mapping ---
+
+// PLAZA_ZONE is the hub zone the Plaza entrances start from.
+const PLAZA_ZONE = "dolpic_base"
+
+// Modes of the autoMapping setting
+const (
+	AUTO_MAPPING_PROPOSE = "propose" // Default: only list what was observed, the user confirms it
+	AUTO_MAPPING_RECORD  = "record"  // Also write high confidence mappings into the save slot right away
+	AUTO_MAPPING_OFF     = "off"
+)
+
+// Confidence of an observed mapping
+const (
+	MAPPING_CONFIDENCE_HIGH = "high" // Source and target zone are both known
+	MAPPING_CONFIDENCE_LOW  = "low"  // The target is known, the source is a guess or one of several candidates
+)
+
+const MAPPING_MAX_PROPOSALS = 50
+
+// MappingProposal is an entrance or exit assignment the tracker observed.
+// Source is an assignment key like the ones in TrackerState.GlobalAssignments:
+// a PlazaShines.ID or "<zone group>::<Exit.ID>". If several sources are possible it is empty and Candidates lists them.
+type MappingProposal struct {
+	ID         int       `json:"id"`
+	Source     string    `json:"source"`
+	Candidates []string  `json:"candidates,omitempty"`
+	Zone       string    `json:"zone"`
+	Confidence string    `json:"confidence"`
+	Recorded   bool      `json:"recorded"` // Already in the save slot, only waiting for confirmation
+	Reason     string    `json:"reason"`
+	Seen       time.Time `json:"seen"`
+}
+
+// MappingObserver watches the zone changes and turns them into mapping proposals for the active save slot.
+type MappingObserver struct {
+	mu        sync.Mutex
+	slots     *SlotManager
+	proposals []MappingProposal
+	nextID    int
+	pending   string // Zone entered from the Plaza whose entrance isn't known yet
+}
+
+func NewMappingObserver(slots *SlotManager) *MappingObserver {
+	return &MappingObserver{slots: slots, nextID: 1}
+}
+
+func (o *MappingObserver) follow() {
+	trackerEvents.Listen(o.handleEvent)
+}
+
+func (o *MappingObserver) handleEvent(ev TrackerEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch ev.Type {
+	case EVENT_SLOT_CHANGED:
+		// Proposals belong to the seed they were seen in
+		o.proposals, o.pending = nil, ""
+	case EVENT_HOOK_LOST:
+		o.pending = ""
+	case EVENT_ZONE_CHANGED:
+		o.pending = ""
+		if currentConfig().autoMappingMode() == AUTO_MAPPING_OFF || ev.Previous == "" || ev.Zone == "" {
+			return
+		}
+		if ev.Previous == PLAZA_ZONE {
+			// The title of the episode usually shows up a moment after the zone
+			o.pending = ev.Zone
+			o.resolveEntrance()
+		} else if ev.Zone != PLAZA_ZONE {
+			o.observeExit(ev.Previous, ev.Zone)
+		}
+	case EVENT_LEVEL_CHANGED, EVENT_EPISODE_CHANGED:
+		if o.pending != "" {
+			o.resolveEntrance()
+		}
+	}
+}
+
+// resolveEntrance looks for the Plaza entrance of the pending zone by the level name and mission title
+// the game shows for the selected episode.
+func (o *MappingObserver) resolveEntrance() {
+	snap := currentSnapshot()
+	world := currentWorld()
+	entrance, vanilla := plazaEntranceForTitle(world, snap.CurrentLevel, snap.CurrentEpisode)
+	if entrance == "" {
+		return
+	}
+	zone := o.pending
+	o.pending = ""
+
+	confidence := MAPPING_CONFIDENCE_HIGH
+	reason := fmt.Sprintf("entered %s from the Plaza after selecting %q", zone, snap.CurrentEpisode)
+	if containsString(vanilla, zone) {
+		// The title might just as well be the one of the zone we landed in
+		confidence = MAPPING_CONFIDENCE_LOW
+		reason += ", which is also the title of that zone"
+	}
+	o.propose(MappingProposal{Source: entrance, Zone: zone, Confidence: confidence, Reason: reason})
+}
+
+// observeExit proposes an exit of zone from that leads to zone to.
+func (o *MappingObserver) observeExit(from, to string) {
+	zone, ok := currentWorld().Zones[from]
+	if !ok || len(zone.Exits) == 0 {
+		return
+	}
+	assignments := o.slots.Active().Get().GlobalAssignments
+	var candidates []string
+	for _, exit := range zone.Exits {
+		key := exitAssignmentKey(from, exit.ID)
+		if assignments[key] == to {
+			return // Already known
+		}
+		if assignments[key] == "" {
+			candidates = append(candidates, key)
+		}
+	}
+
+	reason := fmt.Sprintf("went from %s to %s", from, to)
+	switch {
+	case len(candidates) == 0:
+		return
+	case len(zone.Exits) == 1:
+		o.propose(MappingProposal{Source: candidates[0], Zone: to, Confidence: MAPPING_CONFIDENCE_HIGH, Reason: reason + " through its only exit"})
+	case len(candidates) == 1:
+		o.propose(MappingProposal{Source: candidates[0], Zone: to, Confidence: MAPPING_CONFIDENCE_LOW, Reason: reason + ", the other exits are assigned already"})
+	default:
+		o.propose(MappingProposal{Candidates: candidates, Zone: to, Confidence: MAPPING_CONFIDENCE_LOW, Reason: reason + " through one of several exits"})
+	}
+}
+
+// propose adds a proposal, or refreshes an identical one, and records it right away if the config says so.
+func (o *MappingObserver) propose(p MappingProposal) {
+	p.Seen = time.Now()
+	for i, existing := range o.proposals {
+		if existing.Source == p.Source && existing.Zone == p.Zone && strings.Join(existing.Candidates, ",") == strings.Join(p.Candidates, ",") {
+			o.proposals[i].Seen = p.Seen
+			return
+		}
+	}
+
+	if p.Source != "" {
+		assigned := o.slots.Active().Get().GlobalAssignments[p.Source]
+		if assigned == p.Zone {
+			return
+		}
+		if assigned == "" && p.Confidence == MAPPING_CONFIDENCE_HIGH && currentConfig().autoMappingMode() == AUTO_MAPPING_RECORD {
+			if err := o.record(p.Source, p.Zone, p.Confidence); err != nil {
+				log.Printf("Error recording mapping %s -> %s: %v", p.Source, p.Zone, err)
+			} else {
+				p.Recorded = true
+			}
+		}
+	}
+
+	p.ID = o.nextID
+	o.nextID++
+	o.proposals = append(o.proposals, p)
+	if len(o.proposals) > MAPPING_MAX_PROPOSALS {
+		o.proposals = o.proposals[len(o.proposals)-MAPPING_MAX_PROPOSALS:]
+	}
+	fmt.Printf("Mapping proposed (%s confidence): %s -> %s\n", p.Confidence, p.sourceText(), p.Zone)
+	trackerEvents.Publish(TrackerEvent{Type: EVENT_MAPPING_PROPOSED, Zone: p.Zone})
+}
+
+func (p MappingProposal) sourceText() string {
+	if p.Source != "" {
+		return p.Source
+	}
+	return strings.Join(p.Candidates, " | ")
+}
+
+// record writes an assignment into the active save slot. An empty confidence marks it as confirmed,
+// an empty zone removes the assignment.
+func (o *MappingObserver) record(source, zone, confidence string) error {
+	var target, flag any
+	if zone != "" {
+		target = zone
+	}
+	if confidence != "" {
+		flag = confidence
+	}
+	patch, err := json.Marshal(map[string]any{
+		"globalAssignments": map[string]any{source: target},
+		"autoAssignments":   map[string]any{source: flag},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = o.slots.Active().Patch(patch)
+	return err
+}
+
+// Proposals returns the open proposals, newest last.
+func (o *MappingObserver) Proposals() []MappingProposal {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append(make([]MappingProposal, 0, len(o.proposals)), o.proposals...)
+}
+
+// take removes the proposal with the given ID.
+func (o *MappingObserver) take(id int) (MappingProposal, bool) {
+	for i, p := range o.proposals {
+		if p.ID == id {
+			o.proposals = append(o.proposals[:i], o.proposals[i+1:]...)
+			return p, true
+		}
+	}
+	return MappingProposal{}, false
+}
+
+// Confirm stores the mapping of a proposal (id > 0) or confirms an automatically recorded assignment (source only).
+// For a proposal with several candidates source picks one of them.
+func (o *MappingObserver) Confirm(id int, source string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if id == 0 {
+		state := o.slots.Active().Get()
+		if _, ok := state.AutoAssignments[source]; !ok {
+			return fmt.Errorf("%q is not an automatically recorded assignment", source)
+		}
+		o.dropProposals(source)
+		return o.record(source, state.GlobalAssignments[source], "")
+	}
+
+	p, ok := o.take(id)
+	if !ok {
+		return fmt.Errorf("no proposal with ID %d", id)
+	}
+	if p.Source == "" {
+		if !containsString(p.Candidates, source) {
+			o.proposals = append(o.proposals, p)
+			return fmt.Errorf("pick one of the candidates %s", strings.Join(p.Candidates, ", "))
+		}
+		p.Source = source
+	}
+	o.dropProposals(p.Source)
+	return o.record(p.Source, p.Zone, "")
+}
+
+// Dismiss drops a proposal. If it was recorded already and is still unchanged, the assignment is removed again.
+func (o *MappingObserver) Dismiss(id int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p, ok := o.take(id)
+	if !ok {
+		return fmt.Errorf("no proposal with ID %d", id)
+	}
+	if !p.Recorded {
+		return nil
+	}
+	state := o.slots.Active().Get()
+	if _, auto := state.AutoAssignments[p.Source]; !auto || state.GlobalAssignments[p.Source] != p.Zone {
+		return nil
+	}
+	return o.record(p.Source, "", "")
+}
+
+// dropProposals removes the other proposals for a source that just got assigned.
+func (o *MappingObserver) dropProposals(source string) {
+	kept := o.proposals[:0]
+	for _, p := range o.proposals {
+		if p.Source != source {
+			kept = append(kept, p)
+		}
+	}
+	o.proposals = kept
+}
+
+var trailingDigits = regexp.MustCompile(`[0-9]+$`)
+
+// exitAssignmentKey builds the key the frontend stores exit assignments under: exits are shared
+// by all episodes of a zone group ("bianco5" -> "bianco::warp_47").
+func exitAssignmentKey(zoneID, exitID string) string {
+	return trailingDigits.ReplaceAllString(zoneID, "") + "::" + exitID
+}
+
+// plazaEntranceForTitle finds the Plaza entrance for the level name and mission title the game shows
+// (e.g. "BIANCO HILLS" and "Road to the Big Windmill"). It also returns the zones that carry that
+// title in the unrandomized game.
+func plazaEntranceForTitle(w *WorldData, level, title string) (string, []string) {
+	if title == "" {
+		return "", nil
+	}
+	for _, p := range w.PlazaEntrances {
+		episode, ok := strings.CutPrefix(p.Name, "Episode ")
+		if !ok || !strings.EqualFold(p.GroupName, level) {
+			continue
+		}
+		var vanilla []string
+		for id, zone := range w.Zones {
+			if t, ok := episodeTitle(zone.Name, episode); ok && normalizeLogName(t) == normalizeLogName(title) {
+				vanilla = append(vanilla, id)
+			}
+		}
+		if len(vanilla) > 0 {
+			sort.Strings(vanilla)
+			return p.ID, vanilla
+		}
+	}
+	return "", nil
+}
+
+// episodeTitle returns the mission title of a zone named like "Bianco Hills: Episode 1: Road to the Big Windmill".
+func episodeTitle(zoneName, episode string) (string, bool) {
+	_, rest, ok := strings.Cut(zoneName, "Episode "+episode+": ")
+	if !ok {
+		return "", false
+	}
+	title, _, _ := strings.Cut(rest, ":")
+	return strings.TrimSpace(title), true
+}
+
+// handleMappings lists the open proposals and the unconfirmed automatic assignments of the active slot.
+func handleMappings(o *MappingObserver, slots *SlotManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unconfirmed := slots.Active().Get().AutoAssignments
+		if unconfirmed == nil {
+			unconfirmed = make(map[string]string)
+		}
+		resp := struct {
+			Mode        string            `json:"mode"`
+			Proposals   []MappingProposal `json:"proposals"`
+			Unconfirmed map[string]string `json:"unconfirmed"` // Assignment key -> confidence
+		}{currentConfig().autoMappingMode(), o.Proposals(), unconfirmed}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode mappings", http.StatusInternalServerError)
+		}
+	}
+}
+
+// handleMappingAction confirms (POST {"id": 3} or {"source": "bianco::warp_47"}) or dismisses (POST {"id": 3}) a mapping.
+func handleMappingAction(o *MappingObserver, confirm bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ID     int    `json:"id"`
+			Source string `json:"source"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if confirm {
+			err = o.Confirm(req.ID, req.Source)
+		} else {
+			err = o.Dismiss(req.ID)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestObserver returns a mapping observer with an empty save slot, running with the given autoMapping mode.
+func newTestObserver(t *testing.T, mode string) *MappingObserver {
+	t.Helper()
+	loadTestWorld(t)
+	prevConfig := liveConfig.Load()
+	setConfig(Config{AutoMapping: mode})
+	t.Cleanup(func() { liveConfig.Store(prevConfig) })
+
+	slots, err := OpenSlotManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMappingObserver(slots)
+}
+
+// showTitle makes the scanner report a level name and mission title.
+func showTitle(t *testing.T, level, title string) {
+	prev := publishSnapshot(&ScannerSnapshot{IsHooked: true, CurrentLevel: level, CurrentEpisode: title})
+	t.Cleanup(func() {
+		if prev != nil {
+			publishSnapshot(prev)
+		}
+	})
+}
+
+func TestExitAssignmentKey(t *testing.T) {
+	// Same keys as getZoneGroup(zone) + "::" + exit.id in static/script.js
+	tests := []struct{ zone, exit, want string }{
+		{"bianco5", "warp_47", "bianco::warp_47"},
+		{"delfino2_4", "warp_casino_door_4", "delfino2_::warp_casino_door_4"},
+		{"coro_ex6", "warp_to_bowser_fight", "coro_ex::warp_to_bowser_fight"},
+		{"dolpic_base", "dolpic_base_1", "dolpic_base::dolpic_base_1"},
+		{"pinnaBoss1", "end_mecha_bowser_fight", "pinnaBoss::end_mecha_bowser_fight"},
+	}
+	for _, tt := range tests {
+		if got := exitAssignmentKey(tt.zone, tt.exit); got != tt.want {
+			t.Errorf("exitAssignmentKey(%q, %q) = %q, want %q", tt.zone, tt.exit, got, tt.want)
+		}
+	}
+}
+
+func TestMappingObserverEntrances(t *testing.T) {
+	tests := []struct {
+		name       string
+		zone       string
+		confidence string
+	}{
+		{"randomized", "ricco2", MAPPING_CONFIDENCE_HIGH},
+		{"landed in the zone of the title", "bianco0", MAPPING_CONFIDENCE_LOW},
+		{"secret course", "coro_ex0", MAPPING_CONFIDENCE_HIGH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestObserver(t, AUTO_MAPPING_PROPOSE)
+			showTitle(t, "BIANCO HILLS", "Road to the Big Windmill")
+			o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: tt.zone, Previous: PLAZA_ZONE})
+
+			proposals := o.Proposals()
+			if len(proposals) != 1 {
+				t.Fatalf("proposals = %+v", proposals)
+			}
+			p := proposals[0]
+			if p.Source != "enter_bianco_ep1" || p.Zone != tt.zone || p.Confidence != tt.confidence || p.Recorded {
+				t.Errorf("proposal = %+v, want enter_bianco_ep1 -> %s with %s confidence", p, tt.zone, tt.confidence)
+			}
+		})
+	}
+
+	t.Run("title after the zone", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_PROPOSE)
+		showTitle(t, "DELFINO PLAZA", "")
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "mare3", Previous: PLAZA_ZONE})
+		if len(o.Proposals()) != 0 {
+			t.Fatalf("proposed %+v before the title was known", o.Proposals())
+		}
+		showTitle(t, "BIANCO HILLS", "Road to the Big Windmill")
+		o.handleEvent(TrackerEvent{Type: EVENT_EPISODE_CHANGED, Episode: "Road to the Big Windmill"})
+		if p := o.Proposals(); len(p) != 1 || p[0].Source != "enter_bianco_ep1" || p[0].Zone != "mare3" {
+			t.Errorf("proposals = %+v, want enter_bianco_ep1 -> mare3", p)
+		}
+	})
+
+	t.Run("off", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_OFF)
+		showTitle(t, "BIANCO HILLS", "Road to the Big Windmill")
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "ricco2", Previous: PLAZA_ZONE})
+		if len(o.Proposals()) != 0 {
+			t.Errorf("proposed %+v with autoMapping off", o.Proposals())
+		}
+	})
+}
+
+func TestMappingObserverExits(t *testing.T) {
+	tests := []struct {
+		name       string
+		assigned   map[string]string // Assignments made before
+		from, to   string
+		source     string
+		candidates []string
+		confidence string
+	}{
+		{"only exit", nil, "bianco0", "ricco3", "bianco::warp_55", nil, MAPPING_CONFIDENCE_HIGH},
+		{"several exits", nil, "bianco5", "mare1", "", []string{"bianco::warp_47", "bianco::warp_46"}, MAPPING_CONFIDENCE_LOW},
+		{"other exit assigned", map[string]string{"bianco::warp_47": "sirena0"}, "bianco5", "mare1", "bianco::warp_46", nil, MAPPING_CONFIDENCE_LOW},
+		{"hotel scene", nil, "delfino2_4", "casino0", "delfino2_::warp_casino_door_4", nil, MAPPING_CONFIDENCE_HIGH},
+		{"secret course", nil, "dolpic_ex4", "mare0", "dolpic_ex::warp_1", nil, MAPPING_CONFIDENCE_HIGH},
+		{"Corona Mountain", nil, "coro_ex6", "pinnaBoss1", "coro_ex::warp_to_bowser_fight", nil, MAPPING_CONFIDENCE_HIGH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestObserver(t, AUTO_MAPPING_PROPOSE)
+			if tt.assigned != nil {
+				if _, err := o.slots.Active().Replace(TrackerState{GlobalAssignments: tt.assigned}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: tt.to, Previous: tt.from})
+
+			proposals := o.Proposals()
+			if len(proposals) != 1 {
+				t.Fatalf("proposals = %+v", proposals)
+			}
+			p := proposals[0]
+			if p.Source != tt.source || !slices.Equal(p.Candidates, tt.candidates) || p.Zone != tt.to || p.Confidence != tt.confidence {
+				t.Errorf("proposal = %+v, want %q %v -> %s with %s confidence", p, tt.source, tt.candidates, tt.to, tt.confidence)
+			}
+		})
+	}
+
+	t.Run("known exit", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_PROPOSE)
+		if _, err := o.slots.Active().Replace(TrackerState{GlobalAssignments: map[string]string{"bianco::warp_55": "ricco3"}}); err != nil {
+			t.Fatal(err)
+		}
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "ricco3", Previous: "bianco1"})
+		if len(o.Proposals()) != 0 {
+			t.Errorf("proposed %+v for an assigned exit", o.Proposals())
+		}
+	})
+}
+
+func TestMappingObserverConfirmAndDismiss(t *testing.T) {
+	t.Run("confirm a proposal", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_PROPOSE)
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "ricco3", Previous: "bianco0"})
+		p := o.Proposals()[0]
+		if err := o.Confirm(p.ID, ""); err != nil {
+			t.Fatal(err)
+		}
+		state := o.slots.Active().Get()
+		if state.GlobalAssignments["bianco::warp_55"] != "ricco3" || len(state.AutoAssignments) != 0 {
+			t.Errorf("state after confirming = %+v", state)
+		}
+		if len(o.Proposals()) != 0 {
+			t.Errorf("proposals left: %+v", o.Proposals())
+		}
+	})
+
+	t.Run("confirm one of several candidates", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_PROPOSE)
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "mare1", Previous: "bianco5"})
+		p := o.Proposals()[0]
+		if err := o.Confirm(p.ID, "bianco::warp_55"); err == nil {
+			t.Error("confirmed an exit that isn't a candidate")
+		}
+		if err := o.Confirm(p.ID, "bianco::warp_46"); err != nil {
+			t.Fatal(err)
+		}
+		if got := o.slots.Active().Get().GlobalAssignments; got["bianco::warp_46"] != "mare1" || got["bianco::warp_47"] != "" {
+			t.Errorf("assignments = %v", got)
+		}
+	})
+
+	t.Run("recorded then confirmed", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_RECORD)
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "ricco3", Previous: "bianco0"})
+		if p := o.Proposals(); len(p) != 1 || !p[0].Recorded {
+			t.Fatalf("proposals = %+v, want a recorded one", p)
+		}
+		state := o.slots.Active().Get()
+		if state.GlobalAssignments["bianco::warp_55"] != "ricco3" || state.AutoAssignments["bianco::warp_55"] != MAPPING_CONFIDENCE_HIGH {
+			t.Fatalf("state after recording = %+v", state)
+		}
+		if err := o.Confirm(0, "bianco::warp_55"); err != nil {
+			t.Fatal(err)
+		}
+		state = o.slots.Active().Get()
+		if state.GlobalAssignments["bianco::warp_55"] != "ricco3" || len(state.AutoAssignments) != 0 {
+			t.Errorf("state after confirming = %+v", state)
+		}
+		if err := o.Confirm(0, "bianco::warp_55"); err == nil {
+			t.Error("confirmed an assignment twice")
+		}
+	})
+
+	t.Run("recorded then dismissed", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_RECORD)
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "ricco3", Previous: "bianco0"})
+		if err := o.Dismiss(o.Proposals()[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		state := o.slots.Active().Get()
+		if _, ok := state.GlobalAssignments["bianco::warp_55"]; ok || len(state.AutoAssignments) != 0 {
+			t.Errorf("state after dismissing = %+v", state)
+		}
+		if err := o.Dismiss(1); err == nil {
+			t.Error("dismissed a proposal twice")
+		}
+	})
+
+	t.Run("low confidence isn't recorded", func(t *testing.T) {
+		o := newTestObserver(t, AUTO_MAPPING_RECORD)
+		showTitle(t, "BIANCO HILLS", "Road to the Big Windmill")
+		o.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "bianco0", Previous: PLAZA_ZONE})
+		if p := o.Proposals(); len(p) != 1 || p[0].Recorded {
+			t.Errorf("proposals = %+v, want one that isn't recorded", p)
+		}
+		if got := o.slots.Active().Get().GlobalAssignments; len(got) != 0 {
+			t.Errorf("assignments = %v", got)
+		}
+	})
+}
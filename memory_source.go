@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// --- Memory Sources ---
+
+const (
+	GC_RAM_BASE = 0x80000000
+	GC_RAM_SIZE = 0x1800000 // 24 MiB of emulated main memory
+)
+
+// MemorySource is a backend the DolphinHookManager reads GameCube main memory from.
+// Implementations exist for a live Dolphin process (hook_linux.go / hook_windows.go)
+// and for a static RAM image, which is used for dump files and in-memory fakes.
+type MemorySource interface {
+	// Open attaches to the source. It is called again after the scanner lost the connection.
+	Open() error
+	// Read returns size bytes starting at the given GameCube address (0x80000000 based).
+	Read(gcAddress uint32, size int) ([]byte, error)
+	// Close releases whatever Open acquired.
+	Close()
+}
+
+// MemoryImage is a MemorySource backed by a plain byte slice holding the 24 MiB of main memory.
+type MemoryImage struct {
+	data []byte
+}
+
+// NewMemoryImage creates an empty (zeroed) RAM image, e.g. to build fakes.
+func NewMemoryImage() *MemoryImage {
+	return &MemoryImage{data: make([]byte, GC_RAM_SIZE)}
+}
+
+// LoadMemoryDump reads a raw RAM dump as written by Dolphin ("Dump MRAM") or the dump tools our runners use.
+func LoadMemoryDump(path string) (*MemoryImage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < GC_RAM_SIZE {
+		return nil, fmt.Errorf("%s is %d bytes, expected a %d byte RAM dump", path, len(data), GC_RAM_SIZE)
+	}
+	return &MemoryImage{data: data[:GC_RAM_SIZE]}, nil
+}
+
+// Write places b at the given GameCube address. Only meant for building fakes.
+func (m *MemoryImage) Write(gcAddress uint32, b []byte) {
+	copy(m.data[gcAddress&0x7FFFFFFF:], b)
+}
+
+func (m *MemoryImage) Open() error {
+	return nil
+}
+
+func (m *MemoryImage) Read(gcAddress uint32, size int) ([]byte, error) {
+	offset := int(gcAddress & 0x7FFFFFFF)
+	if size < 0 || offset+size > len(m.data) {
+		return nil, fmt.Errorf("read of %d bytes at 0x%08X is outside of main memory", size, gcAddress)
+	}
+	buffer := make([]byte, size)
+	copy(buffer, m.data[offset:offset+size])
+	return buffer, nil
+}
+
+func (m *MemoryImage) Close() {}
+
+// newMemorySource picks the backend for the scanner based on the config.
+func newMemorySource(cfg Config) MemorySource {
+	if cfg.MemoryDump != "" {
+		img, err := LoadMemoryDump(cfg.MemoryDump)
+		if err != nil {
+			log.Fatalf("Error loading memory dump: %v", err)
+		}
+		fmt.Printf("Reading game memory from dump %s instead of Dolphin.\n", cfg.MemoryDump)
+		return img
+	}
+	return &dolphinProcess{}
+}
+
+// --- Hook Manager Plumbing ---
+
+// Hook attaches the manager to its memory source.
+func (d *DolphinHookManager) Hook() bool {
+	if d.Source == nil {
+		return false
+	}
+	err := d.Source.Open()
+	d.stats.recordHook(err)
+	if err != nil {
+		return false
+	}
+	d.IsHooked = true
+	return true
+}
+
+// Read reads memory at the specified GameCube address from the attached source.
+func (d *DolphinHookManager) Read(gcAddress uint32, size int) ([]byte, error) {
+	if !d.IsHooked {
+		return nil, fmt.Errorf("not hooked")
+	}
+	started := time.Now()
+	data, err := d.Source.Read(gcAddress, size)
+	d.stats.recordRead(time.Since(started), err)
+	return data, err
+}
+
+func (d *DolphinHookManager) Close() {
+	if d.Source != nil {
+		d.Source.Close()
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+)
+
+// loadTestWorld makes the embedded game data the current world.
+func loadTestWorld(t testing.TB) *WorldData {
+	t.Helper()
+	world, err := parseGameData(embeddedGameData)
+	if err != nil {
+		t.Fatalf("loading the embedded game data: %v", err)
+	}
+	setWorld(world)
+	return currentWorld()
+}
+
+// newTestImage returns a RAM image with the game header of gameID and no stage loaded.
+func newTestImage(t testing.TB, gameID string) (*MemoryImage, RegionAddresses) {
+	t.Helper()
+	world := loadTestWorld(t)
+	img := NewMemoryImage()
+	img.Write(ADDR_GAME_ID, []byte(gameID))
+	var addrs RegionAddresses
+	if region := world.region(gameID); region != nil {
+		addrs = region.Addresses
+		img.Write(uint32(addrs.Stage), []byte{NO_STAGE, NO_STAGE})
+	}
+	return img, addrs
+}
+
+// hookImage returns a manager hooked to img that has read the game header.
+func hookImage(t testing.TB, img *MemoryImage) *DolphinHookManager {
+	t.Helper()
+	d := &DolphinHookManager{Source: img, CurrentLevel: "SEARCHING...", StageIndex: NO_STAGE, EpisodeIndex: NO_STAGE}
+	if !d.Hook() {
+		t.Fatal("hooking a memory image failed")
+	}
+	if err := d.SyncGame(); err != nil {
+		t.Fatalf("SyncGame: %v", err)
+	}
+	return d
+}
+
+func be32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func TestMemoryImageGameStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		status string
+	}{
+		{"NTSC-U", []byte("GMSE01"), GAME_STATUS_SUPPORTED},
+		{"release without addresses", []byte("GMSX01"), GAME_STATUS_REGION},
+		{"other game", []byte("GALE01"), GAME_STATUS_OTHER},
+		{"no game", []byte{0, 0, 0, 0, 0, 0}, GAME_STATUS_NO_GAME},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, _ := newTestImage(t, "")
+			img.Write(ADDR_GAME_ID, tt.header)
+			d := hookImage(t, img)
+			if d.GameStatus != tt.status {
+				t.Errorf("game status = %q, want %q", d.GameStatus, tt.status)
+			}
+			if (d.Region != nil) != (tt.status == GAME_STATUS_SUPPORTED) {
+				t.Errorf("region = %v with status %q", d.Region, d.GameStatus)
+			}
+		})
+	}
+}
+
+func TestMemoryImageReadSeed(t *testing.T) {
+	tests := []struct {
+		seed uint32
+		want string
+	}{
+		{0, "00000000"},
+		{0x1A2B3C4D, "1A2B3C4D"},
+		{0xFFFFFFFF, "FFFFFFFF"},
+	}
+	for _, tt := range tests {
+		img, addrs := newTestImage(t, "GMSE01")
+		img.Write(uint32(addrs.Seed), be32(tt.seed))
+		d := hookImage(t, img)
+		got, err := d.ReadSeed()
+		if err != nil {
+			t.Fatalf("ReadSeed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("seed 0x%08X: ReadSeed() = %q, want %q", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryImageGetTotalShines(t *testing.T) {
+	for _, total := range []uint32{0, 1, 42, 120} {
+		img, addrs := newTestImage(t, "GMSE01")
+		img.Write(uint32(addrs.ShinesTotal), be32(total))
+		d := hookImage(t, img)
+		if got := d.GetTotalShines(); got != int(total) {
+			t.Errorf("GetTotalShines() = %d, want %d", got, total)
+		}
+	}
+}
+
+func TestMemoryImageSyncLocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		stage     int
+		text      string // Written at 0x80500000, the level name follows the last zero byte
+		levelAt   uint32
+		wantLevel string
+		wantTitle string
+	}{
+		{"level with mission", NO_STAGE, "\x00Road to the Big Windmill\x00BIANCO HILLS\x00", 0x8050001A, "BIANCO HILLS", "Road to the Big Windmill"},
+		{"level without mission", NO_STAGE, "\x00NOKI BAY\x00", 0x80500001, "NOKI BAY", "???"},
+		{"name inside other text", NO_STAGE, "xNOKI BAY\x00", 0, "SEARCHING...", ""},
+		{"known stage", 2, "\x00Road to the Big Windmill\x00BIANCO HILLS\x00", 0x8050001A, "BIANCO HILLS", "Road to the Big Windmill"},
+		{"known stage without its name", 2, "\x00NOKI BAY\x00", 0, "BIANCO HILLS", "???"}, // Other names aren't searched for
+		{"stage missing in stages.json", 42, "\x00NOKI BAY\x00", 0x80500001, "NOKI BAY", "???"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, addrs := newTestImage(t, "GMSE01")
+			img.Write(uint32(addrs.Stage), []byte{byte(tt.stage), 0})
+			img.Write(0x80500000, []byte(tt.text))
+			d := hookImage(t, img)
+			d.SyncZone()
+			d.SyncLocation()
+			if d.CurrentLevel != tt.wantLevel || d.LevelAddress != tt.levelAt {
+				t.Errorf("level = %q at 0x%08X, want %q at 0x%08X", d.CurrentLevel, d.LevelAddress, tt.wantLevel, tt.levelAt)
+			}
+			if d.CurrentEpisode != tt.wantTitle {
+				t.Errorf("episode = %q, want %q", d.CurrentEpisode, tt.wantTitle)
+			}
+		})
+	}
+}
+
+// loadDumpFixture reads a gzipped RAM dump from testdata.
+func loadDumpFixture(t testing.TB, name string) *MemoryImage {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := NewMemoryImage()
+	img.Write(GC_RAM_BASE, data)
+	return img
+}
+
+// countingSource counts the reads going to a MemorySource.
+type countingSource struct {
+	MemorySource
+	reads, bytes int
+}
+
+func (c *countingSource) Read(gcAddress uint32, size int) ([]byte, error) {
+	c.reads++
+	c.bytes += size
+	return c.MemorySource.Read(gcAddress, size)
+}
+
+// ram_bianco1.bin.gz is a synthetic dump: the GMSE01 header, stage 2 episode 0 (Bianco Hills episode 1), the level
+// name after other text at 0x80812340 and the mission title with the level name at 0x81234560. Everything else is zero.
+
+func TestSyncLocationDump(t *testing.T) {
+	loadTestWorld(t)
+	source := &countingSource{MemorySource: loadDumpFixture(t, "ram_bianco1.bin.gz")}
+	d := hookImage(t, source.MemorySource.(*MemoryImage))
+	d.Source = source
+	d.SyncZone()
+	d.SyncLocation()
+	if d.CurrentLevel != "BIANCO HILLS" || d.CurrentEpisode != "Road to the Big Windmill" || d.LevelAddress != 0x8123457A {
+		t.Fatalf("location = %q / %q at 0x%08X", d.CurrentLevel, d.CurrentEpisode, d.LevelAddress)
+	}
+
+	// The next polls only check the address found
+	source.reads = 0
+	for i := 0; i < 10; i++ {
+		d.SyncLocation()
+	}
+	if source.reads != 10 {
+		t.Errorf("%d reads for 10 polls, want 10", source.reads)
+	}
+}
+
+// BenchmarkSyncLocation measures one poll in a stage stages.json knows right after the stage changed, the polls
+// after that and a poll while the stage index is unknown, which searches for every level name.
+func BenchmarkSyncLocation(b *testing.B) {
+	loadTestWorld(b)
+	img := loadDumpFixture(b, "ram_bianco1.bin.gz")
+	benchmarks := []struct {
+		name   string
+		stage  int
+		cached bool
+	}{
+		{"stage change", 2, false},
+		{"cached", 2, true},
+		{"unknown stage", NO_STAGE, false},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			source := &countingSource{MemorySource: img}
+			d := hookImage(b, img)
+			d.Source = source
+			d.StageIndex, d.EpisodeIndex = bm.stage, 0
+			d.SyncLocation()
+			if d.CurrentLevel != "BIANCO HILLS" {
+				b.Fatalf("level = %q", d.CurrentLevel)
+			}
+			source.reads, source.bytes = 0, 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !bm.cached {
+					d.locationStage = -1
+				}
+				d.SyncLocation()
+			}
+			b.ReportMetric(float64(source.reads)/float64(b.N), "reads/op")
+			b.ReportMetric(float64(source.bytes)/float64(b.N), "readbytes/op")
+		})
+	}
+}
+
+func TestMemoryImageReadOutOfRange(t *testing.T) {
+	img := NewMemoryImage()
+	if _, err := img.Read(GC_RAM_BASE+GC_RAM_SIZE-2, 4); err == nil {
+		t.Error("reading past the end of main memory succeeded")
+	}
+	if b, err := img.Read(GC_RAM_BASE+GC_RAM_SIZE-4, 4); err != nil || len(b) != 4 {
+		t.Errorf("reading the last 4 bytes = %v, %v", b, err)
+	}
+}
+
+func TestRegionTables(t *testing.T) {
+	world, err := parseGameData(overlayGameData("testdata/gamedata/regions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, gameID := range []string{"GMSE01", "GMSP01", "GMSJ01"} {
+		t.Run(gameID, func(t *testing.T) {
+			setWorld(world)
+			addrs := world.region(gameID).Addresses
+			img := NewMemoryImage()
+			img.Write(ADDR_GAME_ID, []byte(gameID))
+			img.Write(uint32(addrs.Stage), []byte{NO_STAGE, NO_STAGE})
+			// Every table gets a different total, at its own address only
+			img.Write(uint32(addrs.ShinesTotal), be32(uint32(len(gameID)+int(gameID[3]))))
+
+			d := &DolphinHookManager{Source: img, StageIndex: NO_STAGE, EpisodeIndex: NO_STAGE}
+			if !d.Hook() {
+				t.Fatal("hooking a memory image failed")
+			}
+			if err := d.SyncGame(); err != nil {
+				t.Fatal(err)
+			}
+			if d.GameStatus != GAME_STATUS_SUPPORTED || d.Region == nil || d.Region.ID != gameID {
+				t.Fatalf("game %s got status %q and region %+v", gameID, d.GameStatus, d.Region)
+			}
+			if got, want := d.GetTotalShines(), len(gameID)+int(gameID[3]); got != want {
+				t.Errorf("GetTotalShines() = %d, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- Session Recording ---
+
+// A recording is a gzip compressed gob stream: one recordingHeader followed by recordedAccess
+// entries. Reads only store the bytes that changed since the recording last saw that memory,
+// so the 4 MiB level-scan blocks don't blow up the file.
+
+const RECORDING_FORMAT = "sms-tracker memory recording v1"
+
+// Kinds of recorded accesses
+const (
+	ACCESS_OPEN  = 1 // The source was opened (hooked)
+	ACCESS_READ  = 2 // A successful read, Patches holds what changed
+	ACCESS_CLOSE = 3 // The source was closed (hook lost)
+)
+
+type recordingHeader struct {
+	Format  string
+	Started time.Time
+}
+
+// memoryPatch is a run of changed bytes, Offset is relative to the read address.
+type memoryPatch struct {
+	Offset uint32
+	Data   []byte
+}
+
+type recordedAccess struct {
+	Time    int64 // Milliseconds since the recording started
+	Kind    uint8
+	Address uint32
+	Size    int
+	Patches []memoryPatch
+}
+
+// diffPatches returns the runs where cur differs from old. Runs closer than 16 bytes are merged.
+func diffPatches(old, cur []byte) []memoryPatch {
+	const mergeGap = 16
+	var patches []memoryPatch
+	for i := 0; i < len(cur); i++ {
+		if old[i] == cur[i] {
+			continue
+		}
+		start, end := i, i+1
+		for j := end; j < len(cur) && j < end+mergeGap; j++ {
+			if old[j] != cur[j] {
+				end = j + 1
+			}
+		}
+		patches = append(patches, memoryPatch{Offset: uint32(start), Data: append([]byte(nil), cur[start:end]...)})
+		i = end - 1
+	}
+	return patches
+}
+
+// recordingSource wraps a MemorySource and writes every access to a recording file.
+type recordingSource struct {
+	inner MemorySource
+
+	mu        sync.Mutex
+	file      *os.File
+	gz        *gzip.Writer
+	enc       *gob.Encoder
+	shadow    *MemoryImage // What a replay of the recording so far has in memory
+	started   time.Time
+	lastFlush time.Time
+}
+
+// NewRecordingSource starts recording all accesses to inner into path.
+func NewRecordingSource(inner MemorySource, path string) (*recordingSource, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &recordingSource{
+		inner:   inner,
+		file:    file,
+		gz:      gzip.NewWriter(file),
+		shadow:  NewMemoryImage(),
+		started: time.Now(),
+	}
+	r.enc = gob.NewEncoder(r.gz)
+	if err := r.enc.Encode(recordingHeader{Format: RECORDING_FORMAT, Started: r.started}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *recordingSource) record(access recordedAccess) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enc == nil {
+		return
+	}
+
+	access.Time = time.Since(r.started).Milliseconds()
+	if err := r.enc.Encode(access); err != nil {
+		log.Printf("Error writing recording: %v", err)
+		return
+	}
+	// Flush regularly so a killed tracker still leaves a usable recording
+	if time.Since(r.lastFlush) > time.Second {
+		r.gz.Flush()
+		r.lastFlush = time.Now()
+	}
+}
+
+func (r *recordingSource) Open() error {
+	if err := r.inner.Open(); err != nil {
+		return err
+	}
+	r.record(recordedAccess{Kind: ACCESS_OPEN})
+	return nil
+}
+
+func (r *recordingSource) Read(gcAddress uint32, size int) ([]byte, error) {
+	data, err := r.inner.Read(gcAddress, size)
+	if err != nil {
+		return data, err
+	}
+	old, err := r.shadow.Read(gcAddress, len(data))
+	if err != nil {
+		// Outside of main memory, nothing a replay could serve
+		return data, nil
+	}
+	if patches := diffPatches(old, data); len(patches) > 0 {
+		r.shadow.Write(gcAddress, data)
+		r.record(recordedAccess{Kind: ACCESS_READ, Address: gcAddress, Size: len(data), Patches: patches})
+	}
+	return data, nil
+}
+
+func (r *recordingSource) Close() {
+	r.inner.Close()
+	r.record(recordedAccess{Kind: ACCESS_CLOSE})
+}
+
+// Finish completes the recording file. Accesses after Finish are no longer recorded.
+func (r *recordingSource) Finish() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enc == nil {
+		return nil
+	}
+	r.enc = nil
+	if err := r.gz.Close(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// --- Session Replay ---
+
+// replaySource is a MemorySource that plays a recording back, Speed times faster than it was recorded.
+// Reads are served from a RAM image the recorded changes are applied to as the replay clock advances.
+type replaySource struct {
+	mu       sync.Mutex
+	dec      *gob.Decoder
+	closer   io.Closer
+	image    *MemoryImage
+	speed    float64
+	started  time.Time
+	next     *recordedAccess // Next access to apply, nil once the recording is exhausted
+	hooked   bool
+	finished bool
+}
+
+// OpenReplay opens a recording for playback. The replay clock starts with the first Open.
+func OpenReplay(path string, speed float64) (*replaySource, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("replay speed must be positive, got %v", speed)
+	}
+	gameID, err := recordingGameID(path)
+	if err != nil {
+		return nil, err
+	}
+	if gameID == "" {
+		return nil, fmt.Errorf("%s never read the game ID, record the session again", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a recording: %w", path, err)
+	}
+
+	r := &replaySource{dec: gob.NewDecoder(gz), closer: file, image: NewMemoryImage(), speed: speed}
+	var header recordingHeader
+	if err := r.dec.Decode(&header); err != nil || header.Format != RECORDING_FORMAT {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a recording", path)
+	}
+	fmt.Printf("Replaying %s (%s, recorded %s) at %gx speed.\n", path, gameID, header.Started.Format(time.DateTime), speed)
+	r.readNext()
+	return r, nil
+}
+
+// recordingGameID returns the game ID the recorded session read from the game header, "" if it never did.
+func recordingGameID(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a recording: %w", path, err)
+	}
+	dec := gob.NewDecoder(gz)
+	var header recordingHeader
+	if err := dec.Decode(&header); err != nil || header.Format != RECORDING_FORMAT {
+		return "", fmt.Errorf("%s is not a recording", path)
+	}
+
+	// The header is read right after hooking, so it is near the start
+	img := NewMemoryImage()
+	for {
+		var access recordedAccess
+		if err := dec.Decode(&access); err != nil {
+			return "", nil
+		}
+		if access.Kind != ACCESS_READ {
+			continue
+		}
+		for _, patch := range access.Patches {
+			img.Write(access.Address+patch.Offset, patch.Data)
+		}
+		if id, _ := img.Read(ADDR_GAME_ID, GAME_ID_SIZE); parseGameID(id) != "" {
+			return string(id), nil
+		}
+	}
+}
+
+// readNext decodes the next access. Callers hold r.mu (or own r exclusively).
+func (r *replaySource) readNext() {
+	var access recordedAccess
+	if err := r.dec.Decode(&access); err != nil {
+		// A tracker that was killed leaves a truncated stream, treat that as the end too
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Printf("Error reading recording: %v", err)
+		}
+		r.next = nil
+		r.closer.Close()
+		return
+	}
+	r.next = &access
+}
+
+// advance applies all accesses up to the current replay time. Callers hold r.mu.
+func (r *replaySource) advance() {
+	if r.started.IsZero() {
+		r.started = time.Now()
+	}
+	now := int64(float64(time.Since(r.started).Milliseconds()) * r.speed)
+
+	for r.next != nil && r.next.Time <= now {
+		switch r.next.Kind {
+		case ACCESS_OPEN:
+			r.hooked = true
+		case ACCESS_CLOSE:
+			r.hooked = false
+		case ACCESS_READ:
+			for _, patch := range r.next.Patches {
+				r.image.Write(r.next.Address+patch.Offset, patch.Data)
+			}
+		}
+		r.readNext()
+	}
+	if r.next == nil && !r.finished {
+		r.finished = true
+		fmt.Println("Replay finished, keeping the last recorded state.")
+	}
+}
+
+func (r *replaySource) Open() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance()
+	if !r.hooked {
+		return fmt.Errorf("replay is not hooked at this point")
+	}
+	return nil
+}
+
+func (r *replaySource) Read(gcAddress uint32, size int) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance()
+	if !r.hooked {
+		return nil, fmt.Errorf("replay lost the hook at this point")
+	}
+	return r.image.Read(gcAddress, size)
+}
+
+// Close does nothing, the replay keeps its position when the scanner drops the hook.
+func (r *replaySource) Close() {}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordSession records a session that hooks img and reads the given addresses, and returns its path.
+func recordSession(t *testing.T, img *MemoryImage, reads ...uint32) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.rec")
+	rec, err := NewRecordingSource(img, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range reads {
+		if _, err := rec.Read(addr, GAME_ID_SIZE); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplayKeepsTheGameID(t *testing.T) {
+	for _, gameID := range []string{"GMSE01", "GMSJ01"} {
+		img, _ := newTestImage(t, gameID)
+		img.Write(0x80500000, []byte("BIANCO"))
+		path := recordSession(t, img, ADDR_GAME_ID, 0x80500000)
+
+		replay, err := OpenReplay(path, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replay.started = time.Now().Add(-time.Minute) // Play the whole recording on Open
+		if err := replay.Open(); err != nil {
+			t.Fatal(err)
+		}
+		for addr, want := range map[uint32]string{ADDR_GAME_ID: gameID, 0x80500000: "BIANCO"} {
+			if got, err := replay.Read(addr, GAME_ID_SIZE); err != nil || string(got) != want {
+				t.Errorf("replayed 0x%08X = %q, %v; want %q", addr, got, err, want)
+			}
+		}
+	}
+}
+
+func TestReplayRejectsRecordingsWithoutGameID(t *testing.T) {
+	img, _ := newTestImage(t, "GMSE01")
+	img.Write(0x80500000, []byte("BIANCO"))
+	path := recordSession(t, img, 0x80500000)
+	if _, err := OpenReplay(path, 1); err == nil {
+		t.Error("replaying a recording that never read the game ID succeeded")
+	}
+}
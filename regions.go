@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- Game Regions ---
+
+// ADDR_GAME_ID is where the disc header, starting with the game ID, is loaded into main memory.
+const ADDR_GAME_ID = 0x80000000
+
+// What the scanner found running in Dolphin
+const (
+	GAME_STATUS_SUPPORTED = "supported"          // Super Mario Sunshine in a region of regions.json
+	GAME_STATUS_REGION    = "unsupported_region" // Super Mario Sunshine, but no address table for its region
+	GAME_STATUS_OTHER     = "other_game"         // Another game is running
+	GAME_STATUS_NO_GAME   = "no_game"            // No game header in memory, e.g. Dolphin is in its game list
+)
+
+// GCAddress is an address in GameCube main memory, written as a hex string ("0x804496AF") in the data files.
+type GCAddress uint32
+
+func (a GCAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%08X", uint32(a)))
+}
+
+func (a *GCAddress) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("address must be a hex string like \"0x80000000\"")
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid address %q", s)
+	}
+	*a = GCAddress(v)
+	return nil
+}
+
+// valid reports whether the address lies in the 24 MiB of main memory.
+func (a GCAddress) valid() bool {
+	return a >= 0x80000000 && a < 0x80000000+0x1800000
+}
+
+// RegionAddresses are the memory locations the scanner reads, which move between the releases of the game.
+type RegionAddresses struct {
+	Skills      GCAddress `json:"skills"`      // One byte per entry of skillNames, non-zero when unlocked
+	Shines      GCAddress `json:"shines"`      // Numeric ID of the shine that unlocks each skill (4 bytes per skill)
+	ShinesTotal GCAddress `json:"shinesTotal"` // Total number of shines collected
+	Flags       GCAddress `json:"flags"`       // Boolean flag bitfield of the flag manager (FLAG_BYTES long)
+	Seed        GCAddress `json:"seed"`        // Randomizer seed
+	Stage       GCAddress `json:"stage"`       // Stage index, followed by the episode index
+}
+
+// invalid returns the names of the addresses that are missing or outside of main memory.
+func (a RegionAddresses) invalid() []string {
+	var names []string
+	for _, addr := range []struct {
+		name  string
+		value GCAddress
+	}{{"skills", a.Skills}, {"shines", a.Shines}, {"shinesTotal", a.ShinesTotal}, {"flags", a.Flags}, {"seed", a.Seed}, {"stage", a.Stage}} {
+		if !addr.value.valid() {
+			names = append(names, addr.name)
+		}
+	}
+	return names
+}
+
+// RegionDefinition is one release of the game, identified by its game ID.
+type RegionDefinition struct {
+	ID        string          `json:"id"` // Game ID, e.g. "GMSE01"
+	Name      string          `json:"name"`
+	Addresses RegionAddresses `json:"addresses"`
+}
+
+// parseRegions reads regions.json. Without it no game is read, so it is required.
+func parseRegions(read gameDataReader) ([]RegionDefinition, error) {
+	regionFile, err := read("regions.json")
+	if err != nil {
+		return nil, fmt.Errorf("reading regions.json: %w", err)
+	}
+	var regionWrapper struct {
+		Regions []RegionDefinition `json:"regions"`
+	}
+	if err := json.Unmarshal(regionFile, &regionWrapper); err != nil {
+		return nil, fmt.Errorf("parsing regions.json: %w", err)
+	}
+	return regionWrapper.Regions, nil
+}
+
+// region returns the address table for a game ID, or nil if there is none or it is incomplete.
+func (w *WorldData) region(gameID string) *RegionDefinition {
+	for i := range w.Regions {
+		if strings.EqualFold(w.Regions[i].ID, gameID) && len(w.Regions[i].Addresses.invalid()) == 0 {
+			return &w.Regions[i]
+		}
+	}
+	return nil
+}
+
+// gameStatus classifies a game ID read from memory.
+func (w *WorldData) gameStatus(gameID string) string {
+	switch {
+	case gameID == "":
+		return GAME_STATUS_NO_GAME
+	case w.region(gameID) != nil:
+		return GAME_STATUS_SUPPORTED
+	case strings.HasPrefix(gameID, "GMS"):
+		return GAME_STATUS_REGION
+	}
+	return GAME_STATUS_OTHER
+}
+
+// SyncGame reads the game ID from the disc header and picks the address table of its region.
+// Region is nil afterwards if the game can't be read; the error is only set if memory couldn't be read at all.
+func (d *DolphinHookManager) SyncGame() error {
+	data, err := d.Read(ADDR_GAME_ID, GAME_ID_SIZE)
+	if err != nil || data == nil {
+		return fmt.Errorf("reading the game ID: %v", err)
+	}
+	gameID := parseGameID(data)
+	world := currentWorld()
+	status := world.gameStatus(gameID)
+	if gameID != d.GameID || status != d.GameStatus {
+		switch status {
+		case GAME_STATUS_REGION:
+			fmt.Printf("Dolphin runs %s, a release of Super Mario Sunshine without (complete) addresses in regions.json. Nothing is read from it.\n", gameID)
+		case GAME_STATUS_OTHER:
+			fmt.Printf("Dolphin runs %s, which is not Super Mario Sunshine. Nothing is read from it.\n", gameID)
+		case GAME_STATUS_SUPPORTED:
+			fmt.Printf("Reading %s.\n", world.region(gameID).Name)
+		}
+	}
+	d.GameID, d.GameStatus = gameID, status
+
+	if status != GAME_STATUS_SUPPORTED {
+		d.Region = nil
+		d.forgetGame()
+		return nil
+	}
+	region := *world.region(gameID)
+	d.Region = &region
+	return nil
+}
+
+// forgetGame drops everything read from the previous game, so nothing of it is reported for another one.
+func (d *DolphinHookManager) forgetGame() {
+	d.CurrentLevel, d.CurrentEpisode = "SEARCHING...", ""
+	d.LevelAddress, d.EpisodeAddress, d.EpisodeNumber = 0, 0, 0
+	d.StageIndex, d.EpisodeIndex, d.CurrentZoneID = NO_STAGE, NO_STAGE, ""
+	d.LastSkills, d.ShineIDs, d.Flags = nil, nil, nil
+	d.TotalShines, d.Seed, d.seedCandidate, d.seedPolls = 0, "", "", 0
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Run Timer ---
+
+const RUNS_DIR = "runs"
+
+// Trigger types for the run timer
+const (
+	TRIGGER_LEVEL  = "level"  // Entering a level, value is the name SyncLocation reports (e.g. "RICCO HARBOR")
+	TRIGGER_ZONE   = "zone"   // Entering a zone, value is a zone ID from zones.json that stages.json resolves to (e.g. "ricco2")
+	TRIGGER_SKILL  = "skill"  // Unlocking a skill, value is one of skillNames
+	TRIGGER_SHINES = "shines" // The shine total reaching a threshold, value is the count
+)
+
+// TimerTrigger describes a condition that starts, splits or finishes the run timer.
+type TimerTrigger struct {
+	Name  string `json:"name,omitempty"` // Segment name, generated from type and value if empty
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type TimerConfig struct {
+	Start  TimerTrigger   `json:"start"`
+	Finish TimerTrigger   `json:"finish"`
+	Splits []TimerTrigger `json:"splits"`
+}
+
+// The run ends with the Bowser fight, not on entering Corona Mountain
+var defaultTimerConfig = TimerConfig{
+	Start:  TimerTrigger{Name: "Airstrip", Type: TRIGGER_LEVEL, Value: "AIRSTRIP"},
+	Finish: TimerTrigger{Name: "Bowser", Type: TRIGGER_ZONE, Value: "coronaBoss"},
+}
+
+// withDefaults fills in the default start and finish triggers for an unset config.
+func (c TimerConfig) withDefaults() TimerConfig {
+	if c.Start.Type == "" {
+		c.Start = defaultTimerConfig.Start
+	}
+	if c.Finish.Type == "" {
+		c.Finish = defaultTimerConfig.Finish
+	}
+	return c
+}
+
+// SegmentName returns the configured name or one generated from the trigger.
+func (t TimerTrigger) SegmentName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	switch t.Type {
+	case TRIGGER_SHINES:
+		return t.Value + " Shines"
+	default:
+		return t.Value
+	}
+}
+
+// matches reports whether the event fulfills the trigger.
+func (t TimerTrigger) matches(ev TrackerEvent) bool {
+	switch t.Type {
+	case TRIGGER_LEVEL:
+		return ev.Type == EVENT_LEVEL_CHANGED && strings.EqualFold(ev.Level, t.Value)
+	case TRIGGER_ZONE:
+		return ev.Type == EVENT_ZONE_CHANGED && strings.EqualFold(ev.Zone, t.Value)
+	case TRIGGER_SKILL:
+		return ev.Type == EVENT_SKILL_UNLOCKED && strings.EqualFold(ev.Skill, t.Value)
+	case TRIGGER_SHINES:
+		threshold, err := strconv.Atoi(t.Value)
+		return err == nil && ev.Type == EVENT_SHINE_TOTAL_CHANGED && ev.ShineTotal >= threshold
+	}
+	return false
+}
+
+// Split is one reached segment of a run.
+type Split struct {
+	Name        string    `json:"name"`
+	At          time.Time `json:"at"`
+	RealTimeMs  int64     `json:"real_time_ms"` // Time since the start of the run
+	SegmentTime int64     `json:"segment_ms"`   // Time since the previous split
+	Trigger     string    `json:"trigger"`      // Type of the trigger that fired
+	Level       string    `json:"level,omitempty"`
+}
+
+// Run is one timed attempt from the start trigger to the finish trigger.
+type Run struct {
+	ID       string     `json:"id"`
+	Seed     string     `json:"seed"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Attempt  int        `json:"attempt"`  // Number of started runs up to and including this one
+	Segments []string   `json:"segments"` // Configured segment names, in order
+	Splits   []Split    `json:"splits"`
+}
+
+// RealTime returns the final time of a finished run or the running time otherwise.
+func (r *Run) RealTime() time.Duration {
+	if r.Finished != nil {
+		return r.Finished.Sub(r.Started)
+	}
+	return time.Since(r.Started)
+}
+
+// RunTimer starts, splits and finishes runs based on tracker events.
+type RunTimer struct {
+	mu        sync.Mutex
+	dir       string
+	cfg       TimerConfig
+	current   *Run
+	fired     map[int]bool // Split triggers already used in the current run
+	completed []*Run
+	attempts  int
+}
+
+// NewRunTimer creates a timer and loads the completed runs stored in dir.
+func NewRunTimer(dir string, cfg TimerConfig) *RunTimer {
+	t := &RunTimer{dir: dir, cfg: cfg.withDefaults()}
+	if finish := t.cfg.Finish; finish.Type == TRIGGER_ZONE && !currentWorld().zoneReachable(finish.Value) {
+		fmt.Printf("No stage in stages.json resolves to zone %s, the run timer won't finish on its own. Add the stage through gameDataDir.\n", finish.Value)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil || run.Finished == nil {
+			continue
+		}
+		t.completed = append(t.completed, &run)
+		t.attempts = max(t.attempts, run.Attempt)
+	}
+	sort.Slice(t.completed, func(i, j int) bool {
+		return t.completed[i].Started.Before(t.completed[j].Started)
+	})
+	return t
+}
+
+// follow drives the timer from the tracker events.
+func (t *RunTimer) follow() {
+	trackerEvents.Listen(t.handleEvent)
+}
+
+func (t *RunTimer) handleEvent(ev TrackerEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == nil {
+		// The start trigger is seen again during a run (e.g. on every visit of the Airstrip),
+		// so only an idle timer starts. A new attempt needs a reset.
+		if t.cfg.Start.matches(ev) {
+			t.start(ev)
+		}
+		return
+	}
+	if t.cfg.Finish.matches(ev) {
+		t.finish(ev)
+		return
+	}
+	for i, trigger := range t.cfg.Splits {
+		if !t.fired[i] && trigger.matches(ev) {
+			t.fired[i] = true
+			t.split(trigger, ev)
+		}
+	}
+}
+
+// start begins a new run. Callers hold t.mu.
+func (t *RunTimer) start(ev TrackerEvent) {
+	t.attempts++
+	segments := make([]string, 0, len(t.cfg.Splits)+1)
+	for _, trigger := range t.cfg.Splits {
+		segments = append(segments, trigger.SegmentName())
+	}
+	segments = append(segments, t.cfg.Finish.SegmentName())
+
+	t.current = &Run{
+		ID:       ev.Time.Format("20060102-150405"),
+		Seed:     currentSnapshot().Seed,
+		Started:  ev.Time,
+		Attempt:  t.attempts,
+		Segments: segments,
+		Splits:   make([]Split, 0),
+	}
+	t.fired = make(map[int]bool)
+	fmt.Println("Run timer started.")
+}
+
+// split records a reached segment. Callers hold t.mu.
+func (t *RunTimer) split(trigger TimerTrigger, ev TrackerEvent) {
+	run := t.current
+	previous := run.Started
+	if len(run.Splits) > 0 {
+		previous = run.Splits[len(run.Splits)-1].At
+	}
+	run.Splits = append(run.Splits, Split{
+		Name:        trigger.SegmentName(),
+		At:          ev.Time,
+		RealTimeMs:  ev.Time.Sub(run.Started).Milliseconds(),
+		SegmentTime: ev.Time.Sub(previous).Milliseconds(),
+		Trigger:     trigger.Type,
+		Level:       ev.Level,
+	})
+}
+
+// finish records the last split and stores the completed run. Callers hold t.mu.
+func (t *RunTimer) finish(ev TrackerEvent) {
+	run := t.current
+	t.split(t.cfg.Finish, ev)
+	finished := ev.Time
+	run.Finished = &finished
+
+	t.completed = append(t.completed, run)
+	t.current = nil
+	fmt.Printf("Run finished in %s.\n", formatRunTime(run.RealTime()))
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(t.dir, run.ID+".json"), data, 0)
+	}
+	if err != nil {
+		log.Printf("Error saving run %s: %v", run.ID, err)
+	}
+}
+
+// Reset drops the running attempt.
+func (t *RunTimer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = nil
+}
+
+// findRun returns the completed run with the given ID, the latest completed run for an empty ID,
+// or the running attempt for "current". Callers hold t.mu.
+func (t *RunTimer) findRun(id string) *Run {
+	if id == "current" {
+		return t.current
+	}
+	if id == "" {
+		if len(t.completed) == 0 {
+			return nil
+		}
+		return t.completed[len(t.completed)-1]
+	}
+	for _, run := range t.completed {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+// --- LiveSplit Export ---
+
+// formatRunTime formats a duration the way LiveSplit stores times (hh:mm:ss.fffffff).
+func formatRunTime(d time.Duration) string {
+	ticks := d.Nanoseconds() / 100 // LiveSplit uses .NET ticks of 100ns
+	h := ticks / (3600 * 1e7)
+	m := ticks / (60 * 1e7) % 60
+	s := ticks / 1e7 % 60
+	return fmt.Sprintf("%02d:%02d:%02d.%07d", h, m, s, ticks%1e7)
+}
+
+type lssTime struct {
+	RealTime string `xml:"RealTime,omitempty"`
+}
+
+type lssSplitTime struct {
+	Name     string `xml:"name,attr"`
+	RealTime string `xml:"RealTime,omitempty"`
+}
+
+type lssSegment struct {
+	Name            string         `xml:"Name"`
+	Icon            string         `xml:"Icon"`
+	SplitTimes      []lssSplitTime `xml:"SplitTimes>SplitTime"`
+	BestSegmentTime lssTime        `xml:"BestSegmentTime"`
+	SegmentHistory  string         `xml:"SegmentHistory"`
+}
+
+type lssAttempt struct {
+	ID       int    `xml:"id,attr"`
+	Started  string `xml:"started,attr"`
+	Ended    string `xml:"ended,attr,omitempty"`
+	RealTime string `xml:"RealTime,omitempty"`
+}
+
+type lssRun struct {
+	XMLName        xml.Name     `xml:"Run"`
+	Version        string       `xml:"version,attr"`
+	GameIcon       string       `xml:"GameIcon"`
+	GameName       string       `xml:"GameName"`
+	CategoryName   string       `xml:"CategoryName"`
+	Offset         string       `xml:"Offset"`
+	AttemptCount   int          `xml:"AttemptCount"`
+	AttemptHistory []lssAttempt `xml:"AttemptHistory>Attempt"`
+	Segments       []lssSegment `xml:"Segments>Segment"`
+	AutoSplitter   string       `xml:"AutoSplitterSettings"`
+}
+
+// toLSS converts a run into a LiveSplit splits file with the run as personal best.
+func (r *Run) toLSS() ([]byte, error) {
+	const lssDate = "01/02/2006 15:04:05"
+
+	reached := make(map[string]Split)
+	for _, split := range r.Splits {
+		reached[split.Name] = split
+	}
+
+	out := lssRun{
+		Version:      "1.7.0",
+		GameName:     "Super Mario Sunshine",
+		CategoryName: "Randomizer",
+		Offset:       "00:00:00",
+		AttemptCount: r.Attempt,
+	}
+
+	attempt := lssAttempt{ID: r.Attempt, Started: r.Started.UTC().Format(lssDate)}
+	if r.Finished != nil {
+		attempt.Ended = r.Finished.UTC().Format(lssDate)
+		attempt.RealTime = formatRunTime(r.RealTime())
+	}
+	out.AttemptHistory = []lssAttempt{attempt}
+
+	for _, name := range r.Segments {
+		segment := lssSegment{Name: name, SplitTimes: []lssSplitTime{{Name: "Personal Best"}}}
+		if split, ok := reached[name]; ok {
+			segment.SplitTimes[0].RealTime = formatRunTime(time.Duration(split.RealTimeMs) * time.Millisecond)
+			segment.BestSegmentTime.RealTime = formatRunTime(time.Duration(split.SegmentTime) * time.Millisecond)
+		}
+		out.Segments = append(out.Segments, segment)
+	}
+
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// --- Timer API ---
+
+// handleTimer reports the running attempt and the completed runs.
+func handleTimer(timer *RunTimer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timer.mu.Lock()
+		resp := struct {
+			Running   bool   `json:"running"`
+			ElapsedMs int64  `json:"elapsed_ms"`
+			Current   *Run   `json:"current"`
+			Completed []*Run `json:"completed"`
+		}{Current: timer.current, Completed: timer.completed}
+		if timer.current != nil {
+			resp.Running = true
+			resp.ElapsedMs = timer.current.RealTime().Milliseconds()
+		}
+		if resp.Completed == nil {
+			resp.Completed = make([]*Run, 0)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(resp)
+		timer.mu.Unlock()
+		if err != nil {
+			http.Error(w, "Failed to encode timer", http.StatusInternalServerError)
+		}
+	}
+}
+
+// handleTimerReset drops the running attempt (POST).
+func handleTimerReset(timer *RunTimer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		timer.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleTimerExport downloads a run: /api/timer/export?run=<id|current>&format=lss|json
+// Without a run ID the latest completed run is exported.
+func handleTimerExport(timer *RunTimer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timer.mu.Lock()
+		defer timer.mu.Unlock()
+
+		run := timer.findRun(r.URL.Query().Get("run"))
+		if run == nil {
+			http.Error(w, "Run not found", http.StatusNotFound)
+			return
+		}
+
+		var (
+			data []byte
+			err  error
+		)
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "", "lss":
+			format = "lss"
+			data, err = run.toLSS()
+			w.Header().Set("Content-Type", "application/xml")
+		case "json":
+			data, err = json.MarshalIndent(run, "", "  ")
+			w.Header().Set("Content-Type", "application/json")
+		default:
+			http.Error(w, "Unknown format, use format=lss or format=json", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to export run: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"sms-run-%s.%s\"", run.ID, format))
+		w.Write(data)
+	}
+}
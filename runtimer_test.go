@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/xml"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunTimerSplits(t *testing.T) {
+	loadTestWorld(t)
+	dir := t.TempDir()
+	timer := NewRunTimer(dir, TimerConfig{
+		Splits: []TimerTrigger{
+			{Type: TRIGGER_SKILL, Value: "HOVER"},
+			{Name: "Ten", Type: TRIGGER_SHINES, Value: "10"},
+		},
+	})
+
+	t0 := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration, ev TrackerEvent) TrackerEvent {
+		ev.Time = t0.Add(d)
+		return ev
+	}
+	for _, ev := range []TrackerEvent{
+		at(0, TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP"}),
+		at(time.Minute, TrackerEvent{Type: EVENT_SKILL_UNLOCKED, Skill: "HOVER"}),
+		at(90*time.Second, TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP"}), // Revisit, the run goes on
+		at(2*time.Minute, TrackerEvent{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: 5}),
+		at(3*time.Minute, TrackerEvent{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: 12}),
+		at(4*time.Minute, TrackerEvent{Type: EVENT_SHINE_TOTAL_CHANGED, ShineTotal: 13}),
+		at(5*time.Minute, TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "CORONA MOUNTAIN"}), // Entering isn't the finale
+		at(6*time.Minute, TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "coronaBoss"}),
+	} {
+		timer.handleEvent(ev)
+	}
+
+	if timer.current != nil {
+		t.Fatal("run still running after the finish trigger")
+	}
+	if len(timer.completed) != 1 {
+		t.Fatalf("%d completed runs, want 1", len(timer.completed))
+	}
+	run := timer.completed[0]
+	if !run.Started.Equal(t0) || run.Attempt != 1 {
+		t.Errorf("run started %v as attempt %d, want %v as attempt 1", run.Started, run.Attempt, t0)
+	}
+	want := []struct {
+		name    string
+		real    time.Duration
+		segment time.Duration
+	}{
+		{"HOVER", time.Minute, time.Minute},
+		{"Ten", 3 * time.Minute, 2 * time.Minute},
+		{"Bowser", 6 * time.Minute, 3 * time.Minute},
+	}
+	if len(run.Splits) != len(want) {
+		t.Fatalf("splits = %+v, want %d", run.Splits, len(want))
+	}
+	for i, w := range want {
+		got := run.Splits[i]
+		if got.Name != w.name || got.RealTimeMs != w.real.Milliseconds() || got.SegmentTime != w.segment.Milliseconds() {
+			t.Errorf("split %d = %s at %dms (segment %dms), want %s at %dms (segment %dms)",
+				i, got.Name, got.RealTimeMs, got.SegmentTime, w.name, w.real.Milliseconds(), w.segment.Milliseconds())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, run.ID+".json")); err != nil {
+		t.Errorf("completed run wasn't saved: %v", err)
+	}
+
+	data, err := run.toLSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lss lssRun
+	if err := xml.Unmarshal(data, &lss); err != nil {
+		t.Fatalf("parsing the exported splits: %v\n%s", err, data)
+	}
+	if lss.AttemptCount != 1 || len(lss.AttemptHistory) != 1 || lss.AttemptHistory[0].RealTime != "00:06:00.0000000" {
+		t.Errorf("attempts = %d %+v, want one attempt of 00:06:00", lss.AttemptCount, lss.AttemptHistory)
+	}
+	if len(lss.Segments) != len(want) {
+		t.Fatalf("%d segments, want %d", len(lss.Segments), len(want))
+	}
+	for i, w := range want {
+		seg := lss.Segments[i]
+		if seg.Name != w.name || seg.SplitTimes[0].RealTime != formatRunTime(w.real) || seg.BestSegmentTime.RealTime != formatRunTime(w.segment) {
+			t.Errorf("segment %d = %s %s (best %s), want %s %s (best %s)", i, seg.Name, seg.SplitTimes[0].RealTime,
+				seg.BestSegmentTime.RealTime, w.name, formatRunTime(w.real), formatRunTime(w.segment))
+		}
+	}
+
+	// The next sighting of the start begins the next attempt
+	timer.handleEvent(at(10*time.Minute, TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP"}))
+	if timer.current == nil || timer.current.Attempt != 2 || !timer.current.Started.Equal(t0.Add(10*time.Minute)) {
+		t.Errorf("second attempt = %+v", timer.current)
+	}
+}
+
+func TestRunTimerLoadsCompletedRuns(t *testing.T) {
+	loadTestWorld(t)
+	dir := t.TempDir()
+	timer := NewRunTimer(dir, TimerConfig{})
+	t0 := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	timer.handleEvent(TrackerEvent{Type: EVENT_LEVEL_CHANGED, Level: "AIRSTRIP", Time: t0})
+	timer.handleEvent(TrackerEvent{Type: EVENT_ZONE_CHANGED, Zone: "coronaBoss", Time: t0.Add(time.Hour)})
+
+	reloaded := NewRunTimer(dir, TimerConfig{})
+	if len(reloaded.completed) != 1 || reloaded.completed[0].RealTime() != time.Hour || reloaded.attempts != 1 {
+		t.Errorf("reloaded runs = %+v, attempts %d", reloaded.completed, reloaded.attempts)
+	}
+}
+
+// TestDefaultTimerFollowsTheScanner plays a run through the memory scanner: the default triggers must fire on
+// what the scanner reports for the Airstrip and the Bowser fight, for the run timer and the LiveSplit splits.
+func TestDefaultTimerFollowsTheScanner(t *testing.T) {
+	img, addrs := newTestImage(t, "GMSE01")
+	saved := dm
+	dm = hookImage(t, img)
+	t.Cleanup(func() { dm = saved })
+	scanOnce()
+
+	timer := NewRunTimer(t.TempDir(), TimerConfig{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	split, err := NewLiveSplitClient(LiveSplitConfig{Address: ln.Addr().String()}, TimerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go split.run()
+	conn, r := acceptLiveSplit(t, ln, split)
+	defer conn.Close()
+
+	_, events, cancel := trackerEvents.Subscribe(eventsNewOnly)
+	defer cancel()
+	enterStage := func(stage, episode byte) {
+		img.Write(uint32(addrs.Stage), []byte{stage, episode})
+		scanOnce()
+		for len(events) > 0 {
+			ev := <-events
+			timer.handleEvent(ev)
+			split.handleEvent(ev)
+		}
+	}
+
+	enterStage(0, 1) // Airstrip
+	if timer.current == nil {
+		t.Fatalf("entering the Airstrip didn't start the timer (zone %q, level %q)", dm.CurrentZoneID, dm.CurrentLevel)
+	}
+	enterStage(1, 0)  // Delfino Plaza
+	enterStage(27, 6) // Corona Mountain
+	if timer.current == nil {
+		t.Fatal("entering Corona Mountain finished the run")
+	}
+	enterStage(32, 0) // Bowser fight
+	if dm.CurrentZoneID != "coronaBoss" {
+		t.Fatalf("zone = %q, want coronaBoss", dm.CurrentZoneID)
+	}
+	if timer.current != nil || len(timer.completed) != 1 {
+		t.Errorf("the Bowser fight didn't finish the run: running %v, %d completed", timer.current != nil, len(timer.completed))
+	}
+	expectCommands(t, conn, r, LIVESPLIT_START, LIVESPLIT_SPLIT)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Per-Seed Save Slots ---
+
+const (
+	SAVES_DIR       = "saves"
+	SLOT_INDEX_FILE = "slots.json"
+	DEFAULT_SLOT    = "default" // Slot used as long as no seed was read from memory
+)
+
+// SaveSlot describes the tracker state stored for one randomizer seed.
+type SaveSlot struct {
+	Seed       string    `json:"seed"`
+	File       string    `json:"file"`
+	Created    time.Time `json:"created"`
+	LastPlayed time.Time `json:"last_played"`
+}
+
+// SlotManager owns one StateStore per seed and switches between them when the scanner sees a new seed.
+type SlotManager struct {
+	mu     sync.Mutex
+	dir    string
+	slots  map[string]*SaveSlot
+	active string
+	store  *StateStore
+}
+
+// OpenSlotManager loads the slot index from dir and activates the default slot.
+func OpenSlotManager(dir string) (*SlotManager, error) {
+	m := &SlotManager{dir: dir, slots: make(map[string]*SaveSlot)}
+
+	data, err := os.ReadFile(filepath.Join(dir, SLOT_INDEX_FILE))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var list []*SaveSlot
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", SLOT_INDEX_FILE, err)
+		}
+		for _, slot := range list {
+			m.slots[slot.Seed] = slot
+		}
+	}
+
+	// Older versions kept a single tracker_state.json next to the saves folder, that one becomes the default slot
+	defaultFile := filepath.Join(dir, slotFileName(DEFAULT_SLOT))
+	legacyFile := filepath.Join(filepath.Dir(dir), STATE_FILE)
+	if _, err := os.Stat(defaultFile); os.IsNotExist(err) {
+		if _, err := os.Stat(legacyFile); err == nil {
+			if err := os.MkdirAll(dir, 0755); err == nil && os.Rename(legacyFile, defaultFile) == nil {
+				fmt.Printf("Moved %s into the default save slot.\n", STATE_FILE)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.activate(DEFAULT_SLOT); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// slotFileName maps a seed to its state file.
+func slotFileName(seed string) string {
+	return safeFileName(seed) + ".json"
+}
+
+// safeFileName makes a seed usable as a file name. Seeds are hex strings, but we never trust them as paths.
+func safeFileName(seed string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, seed)
+}
+
+// Active returns the state store of the currently active slot.
+func (m *SlotManager) Active() *StateStore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store
+}
+
+// ActiveSeed returns the seed of the currently active slot.
+func (m *SlotManager) ActiveSeed() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// Current returns the seed and the state store of the active slot, taken together so they always belong to each other.
+func (m *SlotManager) Current() (string, *StateStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active, m.store
+}
+
+// Activate switches to the slot for seed, creating it if needed.
+func (m *SlotManager) Activate(seed string) error {
+	if seed == "" {
+		seed = DEFAULT_SLOT
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if seed == m.active {
+		m.touch()
+		return nil
+	}
+	if err := m.activate(seed); err != nil {
+		return err
+	}
+	trackerEvents.Publish(TrackerEvent{Type: EVENT_SLOT_CHANGED, Seed: seed})
+	return nil
+}
+
+// activate does the actual switch. Callers hold m.mu.
+func (m *SlotManager) activate(seed string) error {
+	slot, exists := m.slots[seed]
+	if !exists {
+		slot = &SaveSlot{Seed: seed, File: slotFileName(seed), Created: time.Now()}
+	}
+
+	store, err := OpenStateStore(filepath.Join(m.dir, slot.File), STATE_BACKUPS)
+	if err != nil {
+		return err
+	}
+
+	// Whatever was tracked before the hook found a seed belongs to the run of that seed
+	if !exists && m.active == DEFAULT_SLOT && m.store != nil {
+		if pending := m.store.Get(); pending.Revision > 0 {
+			if _, err := store.Replace(pending); err != nil {
+				return err
+			}
+			if _, err := m.store.Replace(TrackerState{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	m.slots[seed] = slot
+	m.active = seed
+	m.store = store
+	m.touch()
+	return nil
+}
+
+// touch marks the active slot as played right now. Callers hold m.mu.
+func (m *SlotManager) touch() {
+	m.slots[m.active].LastPlayed = time.Now()
+	m.saveIndex()
+}
+
+// saveIndex writes the slot list to disk. Callers hold m.mu.
+func (m *SlotManager) saveIndex() {
+	data, err := json.MarshalIndent(m.list(), "", "  ")
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(m.dir, SLOT_INDEX_FILE), data, 0)
+	}
+	if err != nil {
+		log.Printf("Error saving %s: %v", SLOT_INDEX_FILE, err)
+	}
+}
+
+// list returns the slots ordered by the last time they were played. Callers hold m.mu.
+func (m *SlotManager) list() []SaveSlot {
+	list := make([]SaveSlot, 0, len(m.slots))
+	for _, slot := range m.slots {
+		list = append(list, *slot)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastPlayed.After(list[j].LastPlayed)
+	})
+	return list
+}
+
+// List returns a copy of all known slots, most recently played first.
+func (m *SlotManager) List() []SaveSlot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.list()
+}
+
+// followSeed switches slots whenever the scanner reports a new seed.
+func (m *SlotManager) followSeed() {
+	trackerEvents.Listen(m.handleEvent)
+}
+
+func (m *SlotManager) handleEvent(ev TrackerEvent) {
+	if ev.Type != EVENT_SEED_CHANGED {
+		return
+	}
+	if err := m.Activate(ev.Seed); err != nil {
+		log.Printf("Error switching to save slot for seed %s: %v", ev.Seed, err)
+	}
+}
+
+// handleSlots lists the save slots (GET) or switches to one by seed (POST {"seed": "..."}).
+func handleSlots(slots *SlotManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req struct {
+				Seed string `json:"seed"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := slots.Activate(req.Seed); err != nil {
+				http.Error(w, "Failed to switch save slot: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		resp := struct {
+			Active string     `json:"active"`
+			Slots  []SaveSlot `json:"slots"`
+		}{
+			Active: slots.ActiveSeed(),
+			Slots:  slots.List(),
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode save slots", http.StatusInternalServerError)
+		}
+	}
+}
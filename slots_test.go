@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConfirmSeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []string // Seeds read by consecutive passes
+		want  string
+	}{
+		{"read once", []string{"1A2B3C4D"}, ""},
+		{"read twice", []string{"1A2B3C4D", "1A2B3C4D"}, "1A2B3C4D"},
+		{"changing while booting", []string{"00000000", "DEADBEEF", "1A2B3C4D"}, ""},
+		{"zero is no seed", []string{"00000000", "00000000", "00000000"}, ""},
+		{"zero in between", []string{"1A2B3C4D", "00000000", "1A2B3C4D"}, ""},
+		{"new seed replaces the old one", []string{"1A2B3C4D", "1A2B3C4D", "0BADF00D", "0BADF00D"}, "0BADF00D"},
+		{"old seed stays until the new one is confirmed", []string{"1A2B3C4D", "1A2B3C4D", "0BADF00D"}, "1A2B3C4D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DolphinHookManager{}
+			for _, seed := range tt.reads {
+				d.confirmSeed(seed)
+			}
+			if d.Seed != tt.want {
+				t.Errorf("seed after %v = %q, want %q", tt.reads, d.Seed, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlotManagerCurrent(t *testing.T) {
+	m, err := OpenSlotManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seed := range []string{"1A2B3C4D", "0BADF00D", "1A2B3C4D"} {
+		if err := m.Activate(seed); err != nil {
+			t.Fatal(err)
+		}
+		activeSeed, store := m.Current()
+		if activeSeed != seed || store != m.Active() {
+			t.Errorf("Current() = %q, %p after activating %s; active store is %p", activeSeed, store, seed, m.Active())
+		}
+	}
+}
+
+func TestSlotManagerKeepsStatePerSeed(t *testing.T) {
+	m, err := OpenSlotManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tracked before any seed was read, so it belongs to the first seed
+	if _, err := m.Active().Replace(TrackerState{Unlocks: []string{"HOVER"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Activate("1A2B3C4D"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Active().Get().Unlocks; !slices.Equal(got, []string{"HOVER"}) {
+		t.Errorf("first seed starts with unlocks %v, want the ones of the default slot", got)
+	}
+	if _, err := m.Active().Patch([]byte(`{"collectedShines": ["bianco0_1"]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Activate("0BADF00D"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Active().Get(); len(got.Unlocks) != 0 || len(got.CollectedShines) != 0 {
+		t.Errorf("second seed starts with %+v, want an empty state", got)
+	}
+	if _, err := m.Active().Replace(TrackerState{Unlocks: []string{"ROCKET"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Activate("1A2B3C4D"); err != nil {
+		t.Fatal(err)
+	}
+	got := m.Active().Get()
+	if !slices.Equal(got.Unlocks, []string{"HOVER"}) || !slices.Equal(got.CollectedShines, []string{"bianco0_1"}) {
+		t.Errorf("back on the first seed the state is %+v", got)
+	}
+}
+
+func TestSlotManagerMovesLegacyState(t *testing.T) {
+	dir := t.TempDir()
+	legacy := []byte(`{"revision": 3, "unlocks": ["HOVER"]}`)
+	if err := os.WriteFile(filepath.Join(dir, STATE_FILE), legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := OpenSlotManager(filepath.Join(dir, SAVES_DIR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Active().Get(); got.Revision != 3 || !slices.Equal(got.Unlocks, []string{"HOVER"}) {
+		t.Errorf("default slot = %+v, want the legacy state", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, STATE_FILE)); !os.IsNotExist(err) {
+		t.Errorf("%s is still there: %v", STATE_FILE, err)
+	}
+}
+
+func TestSlotManagerWaitsForConfirmedSeed(t *testing.T) {
+	m, err := OpenSlotManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, addrs := newTestImage(t, "GMSE01")
+	d := hookImage(t, img)
+	hub := NewEventHub()
+	_, events, cancel := hub.Subscribe(eventsNewOnly)
+	defer cancel()
+
+	// One scanner pass after another, the slot manager sees the events the scanner publishes
+	prev := d.Snapshot()
+	pass := func(seed uint32) {
+		img.Write(uint32(addrs.Seed), be32(seed))
+		read, err := d.ReadSeed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.confirmSeed(read)
+		next := d.Snapshot()
+		hub.publishChanges(prev, next)
+		prev = next
+		for len(events) > 0 {
+			m.handleEvent(<-events)
+		}
+	}
+
+	for i := 1; i < SEED_CONFIRM_POLLS; i++ {
+		pass(0x1A2B3C4D)
+		if m.ActiveSeed() != DEFAULT_SLOT {
+			t.Fatalf("switched to %s after %d reads of the seed", m.ActiveSeed(), i)
+		}
+	}
+	pass(0x1A2B3C4D)
+	if m.ActiveSeed() != d.Seed || d.Seed == "" {
+		t.Errorf("active slot = %s after the seed was confirmed as %q", m.ActiveSeed(), d.Seed)
+	}
+}
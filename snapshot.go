@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// --- Scanner Snapshots ---
+
+// ScannerSnapshot is an immutable copy of everything the memory scanner knows after one pass.
+// The scanner goroutine is the only writer of the DolphinHookManager; HTTP handlers
+// only ever look at the latest published snapshot and must not modify it.
+type ScannerSnapshot struct {
+	Version        uint64
+	Time           time.Time
+	IsHooked       bool
+	GameID         string
+	GameStatus     string
+	CurrentLevel   string
+	CurrentEpisode string
+	LevelAddress   uint32
+	EpisodeAddress uint32
+	EpisodeNumber  int
+	StageIndex     int
+	EpisodeIndex   int
+	CurrentZoneID  string
+	Skills         []byte
+	ShineIDs       []uint32
+	Flags          []byte
+	TotalShines    int
+	Seed           string
+	Location       LocationScan
+}
+
+var (
+	latestSnapshot  atomic.Pointer[ScannerSnapshot]
+	snapshotVersion atomic.Uint64
+)
+
+func init() {
+	publishSnapshot(&ScannerSnapshot{CurrentLevel: "SEARCHING..."})
+}
+
+// Snapshot copies the current state of the hook manager into a new ScannerSnapshot.
+func (d *DolphinHookManager) Snapshot() *ScannerSnapshot {
+	return &ScannerSnapshot{
+		IsHooked:       d.IsHooked,
+		GameID:         d.GameID,
+		GameStatus:     d.GameStatus,
+		CurrentLevel:   d.CurrentLevel,
+		CurrentEpisode: d.CurrentEpisode,
+		LevelAddress:   d.LevelAddress,
+		EpisodeAddress: d.EpisodeAddress,
+		EpisodeNumber:  d.EpisodeNumber,
+		StageIndex:     d.StageIndex,
+		EpisodeIndex:   d.EpisodeIndex,
+		CurrentZoneID:  d.CurrentZoneID,
+		Skills:         append([]byte(nil), d.LastSkills...),
+		ShineIDs:       append([]uint32(nil), d.ShineIDs...),
+		Flags:          append([]byte(nil), d.Flags...),
+		TotalShines:    d.TotalShines,
+		Seed:           d.Seed,
+		Location:       d.Location,
+	}
+}
+
+// publishSnapshot stamps the snapshot with the next version and makes it visible to readers.
+// It returns the snapshot that was replaced.
+func publishSnapshot(s *ScannerSnapshot) *ScannerSnapshot {
+	s.Version = snapshotVersion.Add(1)
+	s.Time = time.Now()
+	return latestSnapshot.Swap(s)
+}
+
+// currentSnapshot returns the most recently published snapshot. It is never nil.
+func currentSnapshot() *ScannerSnapshot {
+	return latestSnapshot.Load()
+}
+
+// HasSkill reports whether the skill at index i of skillNames is unlocked.
+func (s *ScannerSnapshot) HasSkill(i int) bool {
+	return i < len(s.Skills) && s.Skills[i] != 0
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// --- Skill Spoiler ---
+
+// Spoiler levels for /api/spoiler, from vague to exact
+const (
+	SPOILER_WORLD = "world" // Only the world, e.g. "Bianco Hills"
+	SPOILER_ZONE  = "zone"  // The zone (episode / secret) the shine is in
+	SPOILER_SHINE = "shine" // The exact shine
+)
+
+// UnmappedSkillShine is a skill whose shine ID has no entry in zones.json.
+type UnmappedSkillShine struct {
+	SkillName string `json:"skill_name"`
+	ShineID   uint32 `json:"shine_id"`
+}
+
+type SpoilerResponse struct {
+	Reveal   string               `json:"reveal"`
+	Mappings []SkillMapping       `json:"mappings"`
+	Unmapped []UnmappedSkillShine `json:"unmapped"`
+}
+
+// worldName returns the world part of a zone name ("Bianco Hills: Episode 1: ..." -> "Bianco Hills").
+func worldName(zone Zone) string {
+	name, _, _ := strings.Cut(zone.Name, ":")
+	return strings.TrimSpace(name)
+}
+
+// handleSpoiler reveals which shine unlocks which skill. It has to be enabled in the config
+// and the caller has to ask for a level explicitly: /api/spoiler?reveal=world|zone|shine[&skill=DIVE]
+func handleSpoiler(w http.ResponseWriter, r *http.Request) {
+	if !currentConfig().SpoilerEnabled {
+		http.Error(w, "Spoilers are disabled. Set spoilerEnabled in config.json to use this endpoint.", http.StatusForbidden)
+		return
+	}
+
+	reveal := r.URL.Query().Get("reveal")
+	switch reveal {
+	case SPOILER_WORLD, SPOILER_ZONE, SPOILER_SHINE:
+	default:
+		http.Error(w, "Missing or invalid reveal parameter, use reveal=world, reveal=zone or reveal=shine", http.StatusBadRequest)
+		return
+	}
+	onlySkill := strings.ToUpper(r.URL.Query().Get("skill"))
+
+	snap := currentSnapshot()
+	if len(snap.Skills) == 0 || len(snap.ShineIDs) == 0 {
+		http.Error(w, "Memory data not yet available", http.StatusServiceUnavailable)
+		return
+	}
+
+	resp := SpoilerResponse{Reveal: reveal, Mappings: make([]SkillMapping, 0), Unmapped: make([]UnmappedSkillShine, 0)}
+	seen := make(map[SkillMapping]bool) // Lower levels collapse several shines into the same row
+
+	for i, skill := range skillNames {
+		if i >= len(snap.Skills) || i >= len(snap.ShineIDs) {
+			break
+		}
+		if onlySkill != "" && onlySkill != skill {
+			continue
+		}
+
+		shineID := snap.ShineIDs[i]
+		baseMapping := SkillMapping{
+			SkillName:     skill,
+			HasSkill:      snap.HasSkill(i),
+			OriginalIndex: i, // Store the index from skillNames
+		}
+
+		foundAtLeastOne := false
+		for zoneID, zone := range currentWorld().Zones {
+			for _, shine := range zone.ShinesAvailable {
+				if shine.NumID == NO_SHINE_ID || shine.NumID != int(shineID) {
+					continue
+				}
+				foundAtLeastOne = true
+
+				m := baseMapping
+				m.IsMapped = true
+				m.WorldName = worldName(zone)
+				if reveal == SPOILER_ZONE || reveal == SPOILER_SHINE {
+					m.ZoneID = zoneID
+					m.ZoneName = zone.Name
+				}
+				if reveal == SPOILER_SHINE {
+					m.ShineName = shine.Name
+				}
+
+				key := m
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if reveal == SPOILER_SHINE {
+					m.ShineID = &shineID
+				}
+				resp.Mappings = append(resp.Mappings, m)
+			}
+		}
+
+		if !foundAtLeastOne {
+			m := baseMapping
+			m.ShineID = &shineID
+			resp.Mappings = append(resp.Mappings, m)
+			resp.Unmapped = append(resp.Unmapped, UnmappedSkillShine{SkillName: skill, ShineID: shineID})
+		}
+	}
+
+	// Sort: Primary by OriginalIndex, Secondary by WorldName and ZoneName
+	sort.Slice(resp.Mappings, func(i, j int) bool {
+		a, b := resp.Mappings[i], resp.Mappings[j]
+		if a.OriginalIndex != b.OriginalIndex {
+			return a.OriginalIndex < b.OriginalIndex
+		}
+		if a.WorldName != b.WorldName {
+			return a.WorldName < b.WorldName
+		}
+		return a.ZoneName < b.ZoneName
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "Failed to encode skill mapping", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// --- Spoiler Log Import ---
+
+// Modes of /api/spoiler-log
+const (
+	SPOILER_LOG_APPLY  = "apply"  // Write the entrance assignments of the log into the active save slot
+	SPOILER_LOG_VERIFY = "verify" // Only compare the manual assignments with the log, without revealing it
+)
+
+const SPOILER_LOG_MAX_SIZE = 1 << 20
+
+// spoilerLogEntry is one "entrance -> zone" pair read from a log.
+type spoilerLogEntry struct {
+	Line int // 0 for JSON logs
+	From string
+	To   string
+	Pair string // A whole "Entrance: Zone" line, split once the entrance names are known
+}
+
+// SpoilerLogIssue is a log entry that couldn't be used.
+type SpoilerLogIssue struct {
+	Line   int    `json:"line,omitempty"`
+	Side   string `json:"side"` // "entrance" or "zone"
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+var (
+	logArrows     = []string{"<->", "->", "=>", "→"}
+	logListMarker = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+)
+
+// parseSpoilerLog reads entrance pairs from a log. JSON logs are an object of entrance -> zone names,
+// either at the top level or under "entrances". Text logs have one "Entrance -> Zone" per line
+// ("=>" and "→" work too) or "Entrance: Zone"; other lines are skipped.
+func parseSpoilerLog(data []byte) ([]spoilerLogEntry, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON log: %w", err)
+		}
+		if nested, ok := doc["entrances"]; ok {
+			doc = nil
+			if err := json.Unmarshal(nested, &doc); err != nil {
+				return nil, fmt.Errorf("invalid \"entrances\" in JSON log: %w", err)
+			}
+		}
+		var entries []spoilerLogEntry
+		for from, raw := range doc {
+			var to string
+			if json.Unmarshal(raw, &to) == nil {
+				entries = append(entries, spoilerLogEntry{From: from, To: to})
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].From < entries[j].From })
+		return entries, nil
+	}
+
+	var entries []spoilerLogEntry
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		line = logListMarker.ReplaceAllString(line, "")
+		found := false
+		for _, arrow := range logArrows {
+			if from, to, ok := strings.Cut(line, arrow); ok {
+				entries = append(entries, spoilerLogEntry{Line: i + 1, From: strings.TrimSpace(from), To: strings.TrimSpace(to)})
+				found = true
+				break
+			}
+		}
+		if !found && strings.Contains(line, ":") {
+			entries = append(entries, spoilerLogEntry{Line: i + 1, Pair: line})
+		}
+	}
+	return entries, nil
+}
+
+// normalizeLogName reduces a name to lower case letters and digits, so "Bianco Hills: Episode 1"
+// matches "bianco hills episode 1".
+func normalizeLogName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// logNameIndex maps normalized names to IDs. Names that fit several IDs are dropped.
+type logNameIndex map[string]string
+
+func (idx logNameIndex) add(id string, names ...string) {
+	for _, name := range names {
+		key := normalizeLogName(name)
+		if key == "" {
+			continue
+		}
+		if existing, ok := idx[key]; ok && existing != id {
+			idx[key] = "" // Ambiguous
+			continue
+		}
+		idx[key] = id
+	}
+}
+
+func (idx logNameIndex) lookup(name string) (string, bool) {
+	id := idx[normalizeLogName(name)]
+	return id, id != ""
+}
+
+// entranceIndex knows the Plaza entrances by ID ("enter_bianco_ep1", "bianco_ep1") and by
+// name ("Bianco Hills Episode 1", "Bianco Hills 1", "Corona Mountain").
+func entranceIndex(w *WorldData) logNameIndex {
+	idx := make(logNameIndex)
+	for _, p := range w.PlazaEntrances {
+		idx.add(p.ID, p.ID, strings.TrimPrefix(p.ID, "enter_"), p.GroupName+" "+p.Name)
+		if episode, ok := strings.CutPrefix(p.Name, "Episode "); ok {
+			idx.add(p.ID, p.GroupName+" "+episode, p.GroupName+" Ep "+episode)
+		} else {
+			idx.add(p.ID, p.Name)
+		}
+	}
+	return idx
+}
+
+// zoneIndex knows the zones by ID ("bianco0"), full name, world and episode ("Bianco Hills: Episode 1")
+// and by their own title ("Road to the Big Windmill").
+func zoneIndex(w *WorldData) logNameIndex {
+	idx := make(logNameIndex)
+	for id, zone := range w.Zones {
+		idx.add(id, id, zone.Name)
+		parts := strings.Split(zone.Name, ":")
+		if len(parts) >= 3 {
+			idx.add(id, strings.Join(parts[:2], ":"))
+		}
+		if len(parts) >= 2 {
+			idx.add(id, parts[len(parts)-1])
+		}
+	}
+	return idx
+}
+
+// resolveSpoilerLog translates log entries into assignments (PlazaShines.ID -> Zone.ID).
+func resolveSpoilerLog(w *WorldData, entries []spoilerLogEntry) (map[string]string, []SpoilerLogIssue) {
+	entrances, zones := entranceIndex(w), zoneIndex(w)
+	assignments := make(map[string]string)
+	seenAt := make(map[string]int)
+	issues := make([]SpoilerLogIssue, 0)
+
+	for _, e := range entries {
+		if e.Pair != "" {
+			// Names contain colons too, so split at the first colon that ends an entrance name.
+			// Lines without one are headers ("Seed: ...") or other spoilers and are skipped.
+			var ok bool
+			for i, r := range e.Pair {
+				if r != ':' {
+					continue
+				}
+				if _, ok = entrances.lookup(e.Pair[:i]); ok {
+					e.From, e.To = strings.TrimSpace(e.Pair[:i]), strings.TrimSpace(e.Pair[i+1:])
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+		}
+		entranceID, ok := entrances.lookup(e.From)
+		if !ok {
+			issues = append(issues, SpoilerLogIssue{Line: e.Line, Side: "entrance", Name: e.From, Reason: "unknown entrance"})
+			continue
+		}
+		zoneID, ok := zones.lookup(e.To)
+		if !ok {
+			issues = append(issues, SpoilerLogIssue{Line: e.Line, Side: "zone", Name: e.To, Reason: "unknown zone"})
+			continue
+		}
+		if prev, ok := assignments[entranceID]; ok && prev != zoneID {
+			issues = append(issues, SpoilerLogIssue{Line: e.Line, Side: "entrance", Name: e.From,
+				Reason: fmt.Sprintf("entrance already assigned on line %d", seenAt[entranceID])})
+			continue
+		}
+		assignments[entranceID] = zoneID
+		seenAt[entranceID] = e.Line
+	}
+	return assignments, issues
+}
+
+// SpoilerLogVerification compares the manual assignments with a log without telling the right targets.
+type SpoilerLogVerification struct {
+	Checked    int               `json:"checked"`    // Entrances in the log that are assigned manually
+	Correct    int               `json:"correct"`    // ... and match the log
+	Wrong      []string          `json:"wrong"`      // Entrance IDs assigned to a different zone than in the log
+	Unassigned []string          `json:"unassigned"` // Entrance IDs in the log that aren't assigned yet
+	Issues     []SpoilerLogIssue `json:"issues"`
+}
+
+func verifySpoilerLog(manual, fromLog map[string]string, issues []SpoilerLogIssue) SpoilerLogVerification {
+	v := SpoilerLogVerification{Wrong: make([]string, 0), Unassigned: make([]string, 0), Issues: issues}
+	for entranceID, zoneID := range fromLog {
+		assigned, ok := manual[entranceID]
+		switch {
+		case !ok:
+			v.Unassigned = append(v.Unassigned, entranceID)
+		case assigned == zoneID:
+			v.Checked++
+			v.Correct++
+		default:
+			v.Checked++
+			v.Wrong = append(v.Wrong, entranceID)
+		}
+	}
+	sort.Strings(v.Wrong)
+	sort.Strings(v.Unassigned)
+	// Unknown zone names would reveal where an entrance leads
+	for i := range v.Issues {
+		if v.Issues[i].Side == "zone" {
+			v.Issues[i].Name = ""
+		}
+	}
+	return v
+}
+
+// handleSpoilerLog imports a randomizer spoiler log sent as the request body (POST):
+// /api/spoiler-log?mode=apply fills the entrance assignments of the active save slot,
+// /api/spoiler-log?mode=verify only checks the current assignments against it.
+func handleSpoilerLog(slots *SlotManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mode := r.URL.Query().Get("mode")
+		if mode != SPOILER_LOG_APPLY && mode != SPOILER_LOG_VERIFY {
+			http.Error(w, "Missing or invalid mode parameter, use mode=apply or mode=verify", http.StatusBadRequest)
+			return
+		}
+
+		activeSeed, store := slots.Current()
+		if slot := r.URL.Query().Get("slot"); slot != "" && slot != activeSeed {
+			http.Error(w, fmt.Sprintf("Save slot %s is no longer active (now %s)", slot, activeSeed), http.StatusConflict)
+			return
+		}
+		w.Header().Set("X-Save-Slot", activeSeed)
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, SPOILER_LOG_MAX_SIZE))
+		if err != nil {
+			http.Error(w, "Failed to read log: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		entries, err := parseSpoilerLog(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(entries) == 0 {
+			http.Error(w, "No entrances found in the log", http.StatusUnprocessableEntity)
+			return
+		}
+		assignments, issues := resolveSpoilerLog(currentWorld(), entries)
+		if len(assignments) == 0 {
+			// Nothing matched at all, so the layout isn't one we read rather than a log with a few odd names
+			http.Error(w, fmt.Sprintf("None of the entrances in the log could be matched (%d unknown names), "+
+				"the log layout isn't supported. Convert it to one \"Entrance -> Zone\" per line", len(issues)), http.StatusUnprocessableEntity)
+			return
+		}
+
+		var resp any
+		if mode == SPOILER_LOG_VERIFY {
+			resp = verifySpoilerLog(store.Get().GlobalAssignments, assignments, issues)
+		} else {
+			patch, err := json.Marshal(map[string]any{"globalAssignments": assignments})
+			if err == nil {
+				var state TrackerState
+				state, err = store.Patch(patch)
+				resp = struct {
+					Assigned    int               `json:"assigned"`
+					Assignments map[string]string `json:"assignments"`
+					Issues      []SpoilerLogIssue `json:"issues"`
+					Revision    uint64            `json:"revision"`
+				}{len(assignments), assignments, issues, state.Revision}
+			}
+			if err != nil {
+				http.Error(w, "Failed to save assignments: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode result", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readSpoilerLogFixture(t *testing.T, name string) (map[string]string, []SpoilerLogIssue) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := parseSpoilerLog(data)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return resolveSpoilerLog(loadTestWorld(t), entries)
+}
+
+func TestSpoilerLogText(t *testing.T) {
+	assignments, issues := readSpoilerLogFixture(t, "spoiler_log.txt")
+
+	want := map[string]string{
+		"enter_bianco_ep1": "ricco2",
+		"enter_bianco_ep2": "mare0",
+		"enter_ricco_ep1":  "bianco0",
+		"enter_gelato_ep4": "sirena0",
+		"enter_corona":     "pinnaBoss0",
+	}
+	if !maps.Equal(assignments, want) {
+		t.Errorf("assignments = %v\nwant %v", assignments, want)
+	}
+
+	wantIssues := []SpoilerLogIssue{
+		{Line: 11, Side: "entrance", Name: "Isle Delfino Lighthouse", Reason: "unknown entrance"},
+		{Line: 12, Side: "zone", Name: "Somewhere Unknown", Reason: "unknown zone"},
+		{Line: 13, Side: "entrance", Name: "Bianco Hills Episode 1", Reason: "entrance already assigned on line 6"},
+	}
+	if len(issues) != len(wantIssues) {
+		t.Fatalf("issues = %+v\nwant %+v", issues, wantIssues)
+	}
+	for i := range wantIssues {
+		if issues[i] != wantIssues[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], wantIssues[i])
+		}
+	}
+}
+
+func TestSpoilerLogJSON(t *testing.T) {
+	assignments, issues := readSpoilerLogFixture(t, "spoiler_log.json")
+	want := map[string]string{"enter_bianco_ep1": "ricco2", "enter_noki_ep8": "mareBoss"}
+	if !maps.Equal(assignments, want) {
+		t.Errorf("assignments = %v, want %v", assignments, want)
+	}
+	if len(issues) != 1 || issues[0].Side != "zone" || issues[0].Name != "Not A Zone" {
+		t.Errorf("issues = %+v, want the unknown zone", issues)
+	}
+}
+
+func TestSpoilerLogColons(t *testing.T) {
+	assignments, issues := readSpoilerLogFixture(t, "spoiler_log_colons.txt")
+	want := map[string]string{
+		"enter_bianco_ep1": "ricco2",
+		"enter_bianco_ep2": "mare0",
+		"enter_corona":     "pinnaBoss0",
+	}
+	if !maps.Equal(assignments, want) {
+		t.Errorf("assignments = %v\nwant %v", assignments, want)
+	}
+	// "Seed: ..." and the shine list aren't entrances and are skipped
+	if len(issues) != 1 || issues[0] != (SpoilerLogIssue{Line: 8, Side: "zone", Name: "Somewhere Unknown", Reason: "unknown zone"}) {
+		t.Errorf("issues = %+v, want the unknown zone on line 8", issues)
+	}
+}
+
+func TestSpoilerLogUnknownLayout(t *testing.T) {
+	loadTestWorld(t)
+	slots, err := OpenSlotManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := "Spoilers for seed 1A2B3C4D\nStage bianco -> stage ricco\nStage mamma -> stage mare\n"
+	rec := httptest.NewRecorder()
+	handleSpoilerLog(slots)(rec, httptest.NewRequest(http.MethodPost, "/api/spoiler-log?mode=apply", strings.NewReader(log)))
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "log layout isn't supported") {
+		t.Errorf("importing an unknown layout = %d %q, want 422 naming the layout", rec.Code, rec.Body.String())
+	}
+	if got := slots.Active().Get().GlobalAssignments; len(got) != 0 {
+		t.Errorf("assignments = %v, want none", got)
+	}
+}
+
+func TestSpoilerLogVerifyRevealsNothing(t *testing.T) {
+	fromLog, issues := readSpoilerLogFixture(t, "spoiler_log.txt")
+	manual := map[string]string{
+		"enter_bianco_ep1": "ricco2",  // Right
+		"enter_bianco_ep2": "mare1",   // Wrong
+		"enter_pinna_ep1":  "bianco0", // Not in the log
+	}
+
+	v := verifySpoilerLog(manual, fromLog, issues)
+	if v.Checked != 2 || v.Correct != 1 || len(v.Wrong) != 1 || v.Wrong[0] != "enter_bianco_ep2" {
+		t.Errorf("checked %d, correct %d, wrong %v; want 2, 1, [enter_bianco_ep2]", v.Checked, v.Correct, v.Wrong)
+	}
+	if len(v.Unassigned) != 3 {
+		t.Errorf("unassigned = %v, want the 3 logged entrances without a manual assignment", v.Unassigned)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Neither the zones of the log nor the names of unknown zones may show up
+	secrets := []string{"Somewhere Unknown"}
+	for entrance, zoneID := range fromLog {
+		if manual[entrance] != zoneID {
+			secrets = append(secrets, zoneID, currentWorld().Zones[zoneID].Name)
+		}
+	}
+	for _, secret := range secrets {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("verification reveals %q: %s", secret, out)
+		}
+	}
+}
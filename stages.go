@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// --- Stage Table ---
+
+// The game keeps the loaded stage and its scenario (episode index) as two bytes of TApplication
+// (the stage address of regions.json). The episode index picks the scene archive of the stage, so it
+// follows the file names (bianco5 is Episode 8), not the episode numbers shown on the selection screen.
+const NO_STAGE = 0xFF // Value of both bytes while no stage is loaded
+
+// StageDefinition maps a stage index of the game to zones of zones.json.
+// The zone of an episode is taken from Zones, then ID+episode index ("bianco" + 3), then Default.
+type StageDefinition struct {
+	ID      string            `json:"id"` // Scene archive name of the stage, e.g. "bianco" for bianco0-bianco7
+	Index   int               `json:"index"`
+	Name    string            `json:"name"`
+	Level   string            `json:"level,omitempty"`   // Level name SyncLocation reports in the stage, one of levels
+	Zones   map[string]string `json:"zones,omitempty"`   // Episode index -> zone ID, for scenes that don't follow the ID
+	Default string            `json:"default,omitempty"` // Zone for every other episode index
+}
+
+// parseStages reads stages.json. It is optional, without it no zone is resolved.
+func parseStages(read gameDataReader) ([]StageDefinition, error) {
+	stageFile, err := read("stages.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading stages.json: %w", err)
+	}
+	var stageWrapper struct {
+		Stages []StageDefinition `json:"stages"`
+	}
+	if err := json.Unmarshal(stageFile, &stageWrapper); err != nil {
+		return nil, fmt.Errorf("parsing stages.json: %w", err)
+	}
+	return stageWrapper.Stages, nil
+}
+
+// zoneForStage returns the zone ID for a stage and episode index, or "" if the table doesn't know it.
+func (w *WorldData) zoneForStage(stage, episode int) string {
+	for _, s := range w.Stages {
+		if s.Index != stage {
+			continue
+		}
+		for _, id := range []string{s.Zones[strconv.Itoa(episode)], s.ID + strconv.Itoa(episode), s.Default} {
+			if _, ok := w.Zones[id]; ok {
+				return id
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// levelForStage returns the level name of a stage index, or "" if the table doesn't know it.
+func (w *WorldData) levelForStage(stage int) string {
+	for _, s := range w.Stages {
+		if s.Index == stage {
+			return s.Level
+		}
+	}
+	return ""
+}
+
+// zoneReachable reports whether some stage and episode index resolves to the zone.
+func (w *WorldData) zoneReachable(zoneID string) bool {
+	if _, ok := w.Zones[zoneID]; !ok {
+		return false
+	}
+	for _, s := range w.Stages {
+		if s.Default == zoneID {
+			return true
+		}
+		for _, id := range s.Zones {
+			if id == zoneID {
+				return true
+			}
+		}
+		// Zones named after the stage and an episode index ("bianco" + 5), unless that index is mapped elsewhere
+		episode, err := strconv.Atoi(strings.TrimPrefix(zoneID, s.ID))
+		if err == nil && strings.HasPrefix(zoneID, s.ID) && episode >= 0 && episode < NO_STAGE && w.zoneForStage(s.Index, episode) == zoneID {
+			return true
+		}
+	}
+	return false
+}
+
+var zoneEpisodePattern = regexp.MustCompile(`Episode (\d+)`)
+
+// episodeNumber returns the episode number in the zone's name ("Bianco Hills: Episode 8: ..."), or 0.
+func (w *WorldData) episodeNumber(zoneID string) int {
+	m := zoneEpisodePattern.FindStringSubmatch(w.Zones[zoneID].Name)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// SyncZone reads the stage and episode index and resolves them to a zone of the game data.
+func (d *DolphinHookManager) SyncZone() {
+	data, err := d.Read(uint32(d.Region.Addresses.Stage), 2)
+	if err != nil || data == nil {
+		return
+	}
+	d.StageIndex, d.EpisodeIndex = int(data[0]), int(data[1])
+	if d.StageIndex == NO_STAGE {
+		d.CurrentZoneID, d.EpisodeNumber = "", 0
+		return
+	}
+	world := currentWorld()
+	d.CurrentZoneID = world.zoneForStage(d.StageIndex, d.EpisodeIndex)
+	d.EpisodeNumber = world.episodeNumber(d.CurrentZoneID)
+}
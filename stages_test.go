@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestZoneForStage(t *testing.T) {
+	world := loadTestWorld(t)
+	tests := []struct {
+		stage, episode int
+		zone           string
+		episodeNumber  int
+	}{
+		{2, 0, "bianco0", 1},
+		{2, 5, "bianco5", 8}, // Scene archives don't follow the episode numbers
+		{1, 3, "dolpic_base", 0},
+		{13, 1, "pinnaParco1", 3},
+		{7, 2, "delfino2_4", 4},
+		{27, 6, "coro_ex6", 0},
+		{32, 0, "coronaBoss", 0},
+		{2, 9, "", 0},  // No such scene
+		{42, 0, "", 0}, // Stage missing in stages.json
+		{NO_STAGE, NO_STAGE, "", 0},
+	}
+	for _, tt := range tests {
+		zone := world.zoneForStage(tt.stage, tt.episode)
+		if zone != tt.zone {
+			t.Errorf("zoneForStage(%d, %d) = %q, want %q", tt.stage, tt.episode, zone, tt.zone)
+		}
+		if n := world.episodeNumber(zone); n != tt.episodeNumber {
+			t.Errorf("episodeNumber(%q) = %d, want %d", zone, n, tt.episodeNumber)
+		}
+	}
+}
+
+func TestEveryZoneResolves(t *testing.T) {
+	world := *loadTestWorld(t)
+	for zoneID := range world.Zones {
+		if !world.zoneReachable(zoneID) {
+			t.Errorf("no stage and episode index resolves to zone %s", zoneID)
+		}
+	}
+	for _, p := range validateWorld(&world) {
+		if p.File == "stages.json" {
+			t.Error(p)
+		}
+	}
+
+	// Without the stage of the Bowser fight its zone is reported
+	world.Stages = slices.DeleteFunc(slices.Clone(world.Stages), func(s StageDefinition) bool { return s.ID == "coronaBoss" })
+	if world.zoneReachable("coronaBoss") {
+		t.Error("coronaBoss is reachable without its stage")
+	}
+	var reported bool
+	for _, p := range validateWorld(&world) {
+		reported = reported || (p.File == "stages.json" && p.Path == "stages" && strings.Contains(p.Message, "coronaBoss"))
+	}
+	if !reported {
+		t.Error("the unreachable coronaBoss isn't reported")
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// --- Tracker State Persistence ---
+
+const (
+	STATE_FILE    = "tracker_state.json"
+	STATE_BACKUPS = 5 // Number of rotated backups (tracker_state.json.1 ... .5)
+)
+
+// TrackerState is the progress of a run as edited in the browser.
+// The JSON layout matches the save files the frontend downloads, so those can be PUT as-is.
+type TrackerState struct {
+	Revision           uint64            `json:"revision"`
+	UpdatedAt          time.Time         `json:"updatedAt"`
+	Unlocks            []string          `json:"unlocks"`
+	GlobalAssignments  map[string]string `json:"globalAssignments"`
+	CollectedShines    []string          `json:"collectedShines"`
+	ExcludedShines     []string          `json:"excludedShines"`
+	CollectedBlueCoins []string          `json:"collectedBlueCoins"`
+	CollapsedElements  []string          `json:"collapsedElements"`
+	// Assignments the tracker recorded on its own and the user hasn't confirmed yet, key -> confidence
+	AutoAssignments map[string]string `json:"autoAssignments,omitempty"`
+}
+
+// normalize turns the sets into sorted, duplicate free lists and makes sure nothing is nil.
+func (t *TrackerState) normalize() {
+	for _, set := range []*[]string{&t.Unlocks, &t.CollectedShines, &t.ExcludedShines, &t.CollectedBlueCoins, &t.CollapsedElements} {
+		if *set == nil {
+			*set = []string{}
+		}
+		slices.Sort(*set)
+		*set = slices.Compact(*set)
+	}
+	if t.GlobalAssignments == nil {
+		t.GlobalAssignments = make(map[string]string)
+	}
+	for key, target := range t.GlobalAssignments {
+		// The frontend stores "" when a dropdown is reset
+		if target == "" {
+			delete(t.GlobalAssignments, key)
+		}
+	}
+	for key := range t.AutoAssignments {
+		if _, ok := t.GlobalAssignments[key]; !ok {
+			delete(t.AutoAssignments, key)
+		}
+	}
+}
+
+// clone returns a deep copy so callers can't modify the stored state.
+func (t TrackerState) clone() TrackerState {
+	c := t
+	c.Unlocks = slices.Clone(t.Unlocks)
+	c.CollectedShines = slices.Clone(t.CollectedShines)
+	c.ExcludedShines = slices.Clone(t.ExcludedShines)
+	c.CollectedBlueCoins = slices.Clone(t.CollectedBlueCoins)
+	c.CollapsedElements = slices.Clone(t.CollapsedElements)
+	c.GlobalAssignments = make(map[string]string, len(t.GlobalAssignments))
+	for k, v := range t.GlobalAssignments {
+		c.GlobalAssignments[k] = v
+	}
+	if t.AutoAssignments != nil {
+		c.AutoAssignments = make(map[string]string, len(t.AutoAssignments))
+		for k, v := range t.AutoAssignments {
+			c.AutoAssignments[k] = v
+		}
+	}
+	return c
+}
+
+// StateStore keeps a TrackerState in memory and mirrors every change to a JSON file on disk.
+type StateStore struct {
+	mu      sync.Mutex
+	path    string
+	backups int
+	state   TrackerState
+}
+
+// OpenStateStore loads the state file at path, starting with an empty state if it doesn't exist yet.
+// If the file is missing or can't be parsed, the newest readable backup (path.1 ... path.N) is used instead.
+func OpenStateStore(path string, backups int) (*StateStore, error) {
+	s := &StateStore{path: path, backups: backups}
+	state, err := readStateFile(path)
+	if err != nil || state == nil {
+		for i := 1; i <= backups; i++ {
+			backup := fmt.Sprintf("%s.%d", path, i)
+			if restored, backupErr := readStateFile(backup); backupErr == nil && restored != nil {
+				fmt.Printf("Restored the tracker state from %s\n", backup)
+				state, err = restored, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		s.state = *state
+	}
+	s.state.normalize()
+	return s, nil
+}
+
+// readStateFile parses a state file. It returns nil without an error if the file doesn't exist.
+func readStateFile(path string) (*TrackerState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state TrackerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// Get returns a copy of the current state.
+func (s *StateStore) Get() TrackerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.clone()
+}
+
+// Replace overwrites the whole state (PUT semantics).
+func (s *StateStore) Replace(next TrackerState) (TrackerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit(next)
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the state: present fields replace the stored ones,
+// globalAssignments is merged key by key and a null assignment removes it.
+func (s *StateStore) Patch(patch []byte) (TrackerState, error) {
+	var patchDoc any
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return TrackerState{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := json.Marshal(s.state)
+	if err != nil {
+		return TrackerState{}, err
+	}
+	var doc any
+	if err := json.Unmarshal(current, &doc); err != nil {
+		return TrackerState{}, err
+	}
+	merged, err := json.Marshal(mergePatch(doc, patchDoc))
+	if err != nil {
+		return TrackerState{}, err
+	}
+
+	var next TrackerState
+	if err := json.Unmarshal(merged, &next); err != nil {
+		return TrackerState{}, err
+	}
+	return s.commit(next)
+}
+
+// commit bumps the revision, writes the state to disk and makes it the current one. Callers hold s.mu.
+func (s *StateStore) commit(next TrackerState) (TrackerState, error) {
+	next.normalize()
+	next.Revision = s.state.Revision + 1
+	next.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return TrackerState{}, err
+	}
+	if err := writeFileAtomic(s.path, data, s.backups); err != nil {
+		return TrackerState{}, err
+	}
+
+	s.state = next
+	trackerEvents.Publish(TrackerEvent{Type: EVENT_STATE_CHANGED, Revision: next.Revision})
+	return next.clone(), nil
+}
+
+// mergePatch implements RFC 7396 JSON Merge Patch on decoded JSON values.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so a crash never leaves a half written file. The previous versions are kept as path.1 ... path.N.
+// The current file is linked (or copied) to path.1 rather than moved, so path exists at every moment.
+func writeFileAtomic(path string, data []byte, backups int) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if backups > 0 {
+		if _, err := os.Stat(path); err == nil {
+			for i := backups - 1; i >= 1; i-- {
+				os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+			}
+			if err := backupFile(path, path+".1"); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(tmpName, path)
+}
+
+// backupFile makes backup a hard link to path, or a copy where the file system has no hard links.
+func backupFile(path, backup string) error {
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(path, backup) == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(backup, data, 0644)
+}
+
+// handleState serves the tracker state of the active save slot: GET reads it, PUT replaces it and PATCH merges into it.
+// Writes may name the slot they were meant for (?slot=<seed>) and are refused once a different slot is active.
+func handleState(slots *SlotManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			state TrackerState
+			err   error
+		)
+
+		activeSeed, store := slots.Current()
+		if slot := r.URL.Query().Get("slot"); slot != "" && r.Method != http.MethodGet && slot != activeSeed {
+			http.Error(w, fmt.Sprintf("Save slot %s is no longer active (now %s)", slot, activeSeed), http.StatusConflict)
+			return
+		}
+		w.Header().Set("X-Save-Slot", activeSeed)
+
+		switch r.Method {
+		case http.MethodGet:
+			state = store.Get()
+		case http.MethodPut:
+			var next TrackerState
+			if err := json.NewDecoder(r.Body).Decode(&next); err != nil {
+				http.Error(w, "Invalid state: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			state, err = store.Replace(next)
+		case http.MethodPatch:
+			var patch json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				http.Error(w, "Invalid patch: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			state, err = store.Patch(patch)
+		default:
+			w.Header().Set("Allow", "GET, PUT, PATCH")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			status := http.StatusInternalServerError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				// The patch was valid JSON but doesn't fit the state layout
+				status = http.StatusBadRequest
+			}
+			http.Error(w, "Failed to save state: "+err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(state); err != nil {
+			http.Error(w, "Failed to encode state", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteFileAtomicKeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), STATE_FILE)
+	for _, content := range []string{"one", "two", "three"} {
+		if err := writeFileAtomic(path, []byte(content), 2); err != nil {
+			t.Fatalf("writing %q: %v", content, err)
+		}
+	}
+	for name, want := range map[string]string{path: "three", path + ".1": "two", path + ".2": "one"} {
+		got, err := os.ReadFile(name)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), got, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more backups than asked for: %v", err)
+	}
+}
+
+func TestOpenStateStoreFallsBackToBackup(t *testing.T) {
+	tests := []struct {
+		name  string
+		crash func(path string) error // What is left of the state file after the crash
+	}{
+		{"missing", os.Remove},
+		{"truncated", func(path string) error { return os.WriteFile(path, []byte(`{"collectedShines": [`), 0644) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), STATE_FILE)
+			store, err := OpenStateStore(path, STATE_BACKUPS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Replace(TrackerState{CollectedShines: []string{"bianco1"}}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Replace(TrackerState{CollectedShines: []string{"bianco1", "bianco2"}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.crash(path); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened, err := OpenStateStore(path, STATE_BACKUPS)
+			if err != nil {
+				t.Fatalf("reopening: %v", err)
+			}
+			if got := reopened.Get().CollectedShines; !slices.Equal(got, []string{"bianco1"}) {
+				t.Errorf("restored shines = %v, want the backup [bianco1]", got)
+			}
+		})
+	}
+}
+
+func TestOpenStateStoreWithoutFiles(t *testing.T) {
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), STATE_FILE), STATE_BACKUPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := store.Get(); state.Revision != 0 || len(state.CollectedShines) != 0 {
+		t.Errorf("new store isn't empty: %+v", state)
+	}
+}
@@ -21,6 +21,7 @@ let worldData = {};
 let appState = {
     unlocks: new Set(),
     globalAssignments: {}, // Map<SourceID, TargetZoneID>
+    autoAssignments: {}, // Map<SourceID, Confidence> of assignments the tracker recorded and the user hasn't confirmed
     collectedShines: new Set(),
     excludedShines: new Set(),
     collectedBlueCoins: new Set(),
@@ -53,6 +54,12 @@ function initEventListeners() {
     loadBtn.addEventListener('click', () => fileInput.click());
     fileInput.addEventListener('change', (e) => loadState(e.target));
 
+    const logInput = document.getElementById('log-input');
+    document.getElementById('btn-import-log').addEventListener('click', () => logInput.click());
+    logInput.addEventListener('change', (e) => importSpoilerLog(e.target));
+
+    document.getElementById('mapping-panel').addEventListener('click', handleMappingClick);
+
     // Global Event Delegation for the Tracker Table
     // This replaces individual onclick attributes
     document.getElementById('tracker-table').addEventListener('click', handleTableClick);
@@ -90,8 +97,12 @@ function fetchData() {
             cachedZoneOptionsHTML = '<option value="">-- Select Target --</option>' +
                 sortedZones.map(z => `<option value="${z.id}">${z.name}</option>`).join('');
 
+            return fetchServerState();
+        })
+        .then(() => {
             renderUnlocks();
             renderTable();
+            fetchMappings();
         })
         .catch(err => console.error("Failed to load world data:", err));
 }
@@ -213,6 +224,16 @@ function renderTable() {
 
     tbody.innerHTML = htmlBuffer;
     updateAllStatsUI();
+    highlightCurrentZone();
+}
+
+/**
+ * Marks the rows of the zone the player is in, as resolved by the hook (/api/memory current_zone_id).
+ */
+function highlightCurrentZone() {
+    document.querySelectorAll('tr.current-zone').forEach(row => row.classList.remove('current-zone'));
+    if (!currentZoneID) return;
+    document.querySelectorAll(`tr[data-zone-id="${CSS.escape(currentZoneID)}"]`).forEach(row => row.classList.add('current-zone'));
 }
 
 function buildMainEntryRow(entrance, entryID, groupClass) {
@@ -235,7 +256,7 @@ function buildMainEntryRow(entrance, entryID, groupClass) {
             dropdownHTML = dropdownHTML.replace(`value="${targetZoneID}"`, `value="${targetZoneID}" selected`);
         }
         const selectClass = targetZoneID ? "filled" : "";
-        targetCellContent = `<select class="${selectClass}" data-assign-key="${assignmentKey}">${dropdownHTML}</select>`;
+        targetCellContent = `<select class="${selectClass} ${autoMappedClass(assignmentKey)}" data-assign-key="${assignmentKey}" ${autoMappedTitle(assignmentKey)}>${dropdownHTML}</select>`;
         statsCellContent = `<div class="route-stats" id="stats-${assignmentKey}"></div>`;
     } else {
         // Render a simple Shine button for static Plaza Shines
@@ -316,6 +337,7 @@ function buildRecursiveZoneRows(zoneID, chainHistory, depth, groupClass, parentI
     let rowHTML = `
     <tr class="${groupClass} ${lockedRowClass} ${depth === 0 ? 'entry-main-row' : 'child-row'}" 
         data-parent="${parentID}" 
+        data-zone-id="${zoneID}"
         ${trAction} 
         ${trTarget} 
         ${trKey}
@@ -406,7 +428,7 @@ function buildRecursiveZoneRows(zoneID, chainHistory, depth, groupClass, parentI
             <tr class="${groupClass} ${lockedRowClass} child-row" data-parent="${subParentID}" ${displayStyle}>
                 <td><span class="tree-line">${indent}│   └── </span><span class="exit-name">Exit: ${exit.name}</span></td>
                 <td>
-                    <select class="${selectClass}" data-assign-key="${assignmentKey}" ${disabledAttr}>
+                    <select class="${selectClass} ${autoMappedClass(assignmentKey)}" data-assign-key="${assignmentKey}" ${autoMappedTitle(assignmentKey)} ${disabledAttr}>
                         ${dropdownHTML}
                     </select>
                 </td>
@@ -553,7 +575,9 @@ function handleTableChange(event) {
         const key = target.dataset.assignKey;
         if (key) {
             appState.globalAssignments[key] = target.value;
+            delete appState.autoAssignments[key]; // Picking a target by hand confirms it
             renderTable();
+            scheduleStateSync();
         }
     }
 }
@@ -597,6 +621,8 @@ function handleTableClick(event) {
             appState.collectedShines.add(id);
         }
 
+        scheduleStateSync();
+
         const isCoronaUnlocked = checkCoronaUnlock();
         if (wasCoronaUnlocked !== isCoronaUnlocked) {
             if (isCoronaUnlocked) appState.collapsedElements.delete("corona-main");
@@ -625,6 +651,7 @@ function handleTableClick(event) {
         } else {
             appState.collectedBlueCoins.add(id);
         }
+        scheduleStateSync();
 
         const allInstances = document.querySelectorAll(`[data-action="toggle-bc"][data-id="${id}"]`);
         allInstances.forEach(el => {
@@ -664,6 +691,7 @@ function handleCollapse(selector, element, storageKey) {
     if (icon) icon.innerText = shouldHide ? '▶' : '▼';
 
     element.classList.toggle('collapsed', shouldHide);
+    scheduleStateSync();
 }
 
 // --- Stats & Helpers ---
@@ -888,16 +916,36 @@ function getZoneGroup(zoneID) {
 
 // --- Persistence ---
 
-function saveState() {
-    const exportData = {
+function buildExportData() {
+    return {
         unlocks: Array.from(appState.unlocks),
         globalAssignments: appState.globalAssignments,
+        autoAssignments: appState.autoAssignments,
         collectedShines: Array.from(appState.collectedShines),
         excludedShines: Array.from(appState.excludedShines),
         collectedBlueCoins: Array.from(appState.collectedBlueCoins),
         collapsedElements: Array.from(appState.collapsedElements),
         timestamp: new Date().toISOString()
     };
+}
+
+// Converts a save file / server state object back into appState
+function applyStateData(importedData) {
+    appState.unlocks = new Set(importedData.unlocks || []);
+    appState.collectedShines = new Set(importedData.collectedShines || []);
+    appState.excludedShines = new Set(importedData.excludedShines || []);
+    appState.collectedBlueCoins = new Set(importedData.collectedBlueCoins || []);
+    appState.collapsedElements = new Set(importedData.collapsedElements || []);
+    appState.globalAssignments = importedData.globalAssignments || {};
+    appState.autoAssignments = importedData.autoAssignments || {};
+
+    if(!appState.globalAssignments["enter_corona"]) {
+        appState.globalAssignments["enter_corona"] = "coro_ex6";
+    }
+}
+
+function saveState() {
+    const exportData = buildExportData();
 
     const blob = new Blob([JSON.stringify(exportData, null, 2)], {type: 'application/json'});
     const url = URL.createObjectURL(blob);
@@ -920,20 +968,12 @@ function loadState(inputElement) {
             const importedData = JSON.parse(e.target.result);
 
             // Convert Arrays back to Sets
-            appState.unlocks = new Set(importedData.unlocks || []);
-            appState.collectedShines = new Set(importedData.collectedShines || []);
-            appState.excludedShines = new Set(importedData.excludedShines || []);
-            appState.collectedBlueCoins = new Set(importedData.collectedBlueCoins || []);
-            appState.collapsedElements = new Set(importedData.collapsedElements || []);
-            appState.globalAssignments = importedData.globalAssignments || {};
-
-            if(!appState.globalAssignments["enter_corona"]) {
-                appState.globalAssignments["enter_corona"] = "coro_ex6";
-            }
+            applyStateData(importedData);
 
             renderUnlocks();
             renderTable();
             updateAllStatsUI();
+            scheduleStateSync();
 
             alert("Save loaded successfully!");
         } catch (err) {
@@ -945,6 +985,210 @@ function loadState(inputElement) {
     inputElement.value = '';
 }
 
+// Fill in the Plaza entrances from a randomizer spoiler log, or only check the current ones against it
+async function importSpoilerLog(inputElement) {
+    const file = inputElement.files?.[0];
+    if (!file) return;
+    inputElement.value = '';
+
+    const apply = confirm("Fill in all entrance assignments from this log?\n\nCancel only checks your current assignments against the log without revealing anything.");
+
+    // Push pending local changes first, otherwise they would overwrite the imported assignments
+    if (stateSyncTimeout) {
+        clearTimeout(stateSyncTimeout);
+        await pushServerState();
+    }
+
+    try {
+        const mode = apply ? 'apply' : 'verify';
+        const r = await fetch(`/api/spoiler-log?mode=${mode}&slot=${encodeURIComponent(serverSlot)}`, {
+            method: 'POST',
+            body: await file.text()
+        });
+        if (!r.ok) throw new Error(await r.text());
+        const result = await r.json();
+        const skipped = result.issues.length ? `\n${result.issues.length} lines of the log could not be matched (see the browser console).` : '';
+        if (result.issues.length) console.warn("Spoiler log issues:", result.issues);
+
+        if (apply) {
+            await fetchServerState();
+            renderTable();
+            alert(`Assigned ${result.assigned} entrances from the log.${skipped}`);
+        } else {
+            const entranceName = id => {
+                const p = worldData.plaza_entrances.find(e => e.id === id);
+                return p ? `${p.group_name} ${p.name}` : id;
+            };
+            const wrong = result.wrong.length ? `\n\nWrong:\n${result.wrong.map(entranceName).join('\n')}` : '';
+            alert(`${result.correct} of ${result.checked} assigned entrances match the log, ${result.unassigned.length} are not assigned yet.${wrong}${skipped}`);
+        }
+    } catch (err) {
+        console.error(err);
+        alert("Error importing spoiler log: " + err.message);
+    }
+}
+
+// --- Automatic Mapping ---
+// The tracker proposes entrance and exit assignments it observed while playing (/api/mappings).
+
+function autoMappedClass(key) {
+    return appState.autoAssignments[key] ? "auto-mapped" : "";
+}
+
+function autoMappedTitle(key) {
+    const confidence = appState.autoAssignments[key];
+    return confidence ? `title="Recorded automatically (${confidence} confidence), confirm it above or pick a target"` : "";
+}
+
+function mappingSourceName(key) {
+    const entrance = worldData.plaza_entrances?.find(e => e.id === key);
+    if (entrance) return `${entrance.group_name} ${entrance.name}`;
+    const [group, exitID] = key.split('::');
+    for (const zone of Object.values(worldData.zones || {})) {
+        if (getZoneGroup(zone.id) !== group) continue;
+        const exit = zone.exits?.find(e => e.id === exitID);
+        if (exit) return `${zone.name.split(':')[0]} – Exit: ${exit.name}`;
+    }
+    return key;
+}
+
+function mappingZoneName(zoneID) {
+    return worldData.zones?.[zoneID]?.name || zoneID;
+}
+
+async function fetchMappings() {
+    try {
+        const r = await fetch('/api/mappings');
+        if (!r.ok) throw new Error(await r.text());
+        renderMappings(await r.json());
+    } catch (err) {
+        console.error("Failed to load mapping proposals:", err);
+    }
+}
+
+function renderMappings(data) {
+    const panel = document.getElementById('mapping-panel');
+    const proposed = new Set(data.proposals.map(p => p.source));
+    let html = "";
+
+    data.proposals.forEach(p => {
+        const source = p.source
+            ? mappingSourceName(p.source)
+            : `<select data-mapping-candidates="${p.id}">${p.candidates.map(c => `<option value="${c}">${mappingSourceName(c)}</option>`).join('')}</select>`;
+        html += `
+        <div class="mapping-row confidence-${p.confidence}" title="${p.reason}">
+            <span>${source} → ${mappingZoneName(p.zone)}</span>
+            <span class="mapping-confidence">${p.recorded ? 'recorded, ' : ''}${p.confidence}</span>
+            <button class="btn" data-mapping-action="confirm" data-id="${p.id}">✓</button>
+            <button class="btn" data-mapping-action="dismiss" data-id="${p.id}">✕</button>
+        </div>`;
+    });
+    // Automatic assignments from an earlier session have no proposal anymore
+    Object.entries(data.unconfirmed).filter(([key]) => !proposed.has(key)).forEach(([key, confidence]) => {
+        html += `
+        <div class="mapping-row confidence-${confidence}">
+            <span>${mappingSourceName(key)} → ${mappingZoneName(appState.globalAssignments[key])}</span>
+            <span class="mapping-confidence">recorded, ${confidence}</span>
+            <button class="btn" data-mapping-action="confirm" data-source="${key}">✓</button>
+        </div>`;
+    });
+
+    panel.innerHTML = html ? `<span class="mapping-label">Observed mappings:</span>${html}` : "";
+    panel.style.display = html ? "" : "none";
+}
+
+async function handleMappingClick(event) {
+    const button = event.target.closest('[data-mapping-action]');
+    if (!button) return;
+
+    const body = {id: Number(button.dataset.id || 0), source: button.dataset.source || ""};
+    const candidates = document.querySelector(`[data-mapping-candidates="${body.id}"]`);
+    if (candidates) body.source = candidates.value;
+
+    // Push pending local changes first, otherwise they would overwrite the confirmed assignment
+    if (stateSyncTimeout) {
+        clearTimeout(stateSyncTimeout);
+        await pushServerState();
+    }
+    try {
+        const r = await fetch(`/api/mappings/${button.dataset.mappingAction}`, {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(body)
+        });
+        if (!r.ok) throw new Error(await r.text());
+        await fetchServerState();
+        renderTable();
+        fetchMappings();
+    } catch (err) {
+        console.error(err);
+        alert("Error updating mapping: " + err.message);
+    }
+}
+
+// --- Server Side State ---
+// Every change is mirrored to /api/state so a refresh, a crash or a second device never loses a run.
+
+let serverRevision = 0;
+let serverSlot = "";
+let stateSyncTimeout = null;
+
+async function fetchServerState(applyEmpty = false) {
+    try {
+        const r = await fetch('/api/state');
+        if (!r.ok) throw new Error(await r.text());
+        const data = await r.json();
+        serverRevision = data.revision || 0;
+        serverSlot = r.headers.get('X-Save-Slot') || "";
+        // A revision of 0 means nothing was stored yet, keep the defaults unless we switched slots
+        if (serverRevision > 0 || applyEmpty) applyStateData(data);
+    } catch (err) {
+        console.error("Failed to load tracker state:", err);
+    }
+}
+
+function scheduleStateSync() {
+    if (stateSyncTimeout) clearTimeout(stateSyncTimeout);
+    stateSyncTimeout = setTimeout(pushServerState, 500);
+}
+
+async function pushServerState() {
+    stateSyncTimeout = null;
+    try {
+        const r = await fetch(`/api/state?slot=${encodeURIComponent(serverSlot)}`, {
+            method: 'PUT',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(buildExportData())
+        });
+        if (r.status === 409) return; // The save slot changed under us, its state gets loaded via slot_changed
+        if (!r.ok) throw new Error(await r.text());
+        const data = await r.json();
+        serverRevision = data.revision;
+    } catch (err) {
+        console.error("Failed to store tracker state:", err);
+    }
+}
+
+// Another tab or device changed the state, pull it unless we have local changes pending
+async function onServerStateChanged(revision) {
+    if (revision <= serverRevision || stateSyncTimeout) return;
+    await fetchServerState();
+    renderUnlocks();
+    renderTable();
+}
+
+// The scanner found a different seed, so the server switched to that seed's save slot
+async function onSaveSlotChanged() {
+    if (stateSyncTimeout) {
+        clearTimeout(stateSyncTimeout);
+        stateSyncTimeout = null;
+    }
+    await fetchServerState(true);
+    renderUnlocks();
+    renderTable();
+    fetchMappings();
+}
+
 // --- Auto-Tracker Logic ---
 
 let autoTrackInterval = null;
@@ -952,6 +1196,7 @@ let countdownInterval = null;
 let currentTrackingInterval = 5; // Will be updated by API
 let nextUpdateIn = 5.0;
 let isFirstLoad = true;
+let currentZoneID = ""; // Zone ID the hook resolved, highlighted in the table
 
 // Handle UI interaction
 document.getElementById('chk-auto-track').addEventListener('change', (e) => {
@@ -970,6 +1215,36 @@ function initAutoTracker() {
     fetchMemoryData().then(() => {
         isFirstLoad = false;
     });
+    initEventStream();
+}
+
+/**
+ * Subscribes to the live scanner events so changes show up immediately instead of on the next poll.
+ * EventSource reconnects on its own and resumes via Last-Event-ID.
+ */
+const SCANNER_EVENT_TYPES = [
+    "hook_connected", "hook_lost", "level_changed", "episode_changed", "zone_changed",
+    "skill_unlocked", "skill_lost", "seed_changed", "shine_collected",
+    "blue_coin_collected", "config_changed"
+];
+
+function initEventStream() {
+    if (!window.EventSource) return;
+
+    const source = new EventSource('/api/events');
+    source.addEventListener('state_changed', (e) => {
+        onServerStateChanged(JSON.parse(e.data).revision);
+    });
+    source.addEventListener('slot_changed', onSaveSlotChanged);
+    source.addEventListener('data_reloaded', fetchData);
+    source.addEventListener('mapping_proposed', fetchMappings);
+    SCANNER_EVENT_TYPES.forEach(type => {
+        source.addEventListener(type, () => {
+            if (!appState.autoTrackEnabled) return;
+            nextUpdateIn = currentTrackingInterval;
+            fetchMemoryData();
+        });
+    });
 }
 
 function startAutoTracking(isSilentUpdate = false) {
@@ -1041,12 +1316,14 @@ async function fetchMemoryData() {
             return;
         }
 
-        updateDolphinStatusUI(data.is_hooked);
+        updateDolphinStatusUI(data.is_hooked, data.game_status, data.game_id);
 
-        if (!data.is_hooked) {
+        // Another game or a region without addresses is not read at all
+        if (!data.is_hooked || data.game_status !== "supported") {
             document.getElementById('current-location').innerText = "SEARCHING...";
             document.getElementById('current-seed').innerText = "Searching..."; // Updated
             document.getElementById('current-episode').innerText = "---";
+            setCurrentZone("");
             return;
         }
 
@@ -1054,6 +1331,7 @@ async function fetchMemoryData() {
         document.getElementById('current-location').innerText = data.current_level || "---";
         document.getElementById('current-seed').innerText = data.seed || "---"; // Updated
         document.getElementById('current-episode').innerText = data.current_episode || "---";
+        setCurrentZone(data.current_zone_id || "");
 
         let changed = false;
         if (data.unlocks) {
@@ -1069,9 +1347,29 @@ async function fetchMemoryData() {
             }
         }
 
+        // Shines and blue coins are only ever added, manual checks for shines without a hook mapping stay untouched
+        if (data.collected_shines) {
+            data.collected_shines.forEach(shineID => {
+                if (!appState.collectedShines.has(shineID)) {
+                    appState.collectedShines.add(shineID);
+                    appState.excludedShines.delete(shineID);
+                    changed = true;
+                }
+            });
+        }
+        if (data.collected_blue_coins) {
+            data.collected_blue_coins.forEach(bcID => {
+                if (!appState.collectedBlueCoins.has(bcID)) {
+                    appState.collectedBlueCoins.add(bcID);
+                    changed = true;
+                }
+            });
+        }
+
         if (changed) {
             renderUnlocks();
             renderTable();
+            scheduleStateSync();
         }
         syncUnlockIconsVisuals(data.unlocks);
 
@@ -1081,12 +1379,26 @@ async function fetchMemoryData() {
     }
 }
 
-function updateDolphinStatusUI(isHooked) {
+function setCurrentZone(zoneID) {
+    if (zoneID === currentZoneID) return;
+    currentZoneID = zoneID;
+    highlightCurrentZone();
+}
+
+function updateDolphinStatusUI(isHooked, gameStatus, gameID) {
     const indicator = document.getElementById('dolphin-indicator');
     const text = document.getElementById('dolphin-text');
     if (!indicator || !text) return;
 
-    if (isHooked) {
+    if (isHooked && gameStatus && gameStatus !== "supported") {
+        indicator.style.background = "#f39c12";
+        indicator.style.boxShadow = "none";
+        text.innerText = {
+            unsupported_region: `Unsupported Region (${gameID})`,
+            other_game: `Not Sunshine (${gameID})`,
+        }[gameStatus] || "No Game Running";
+        text.style.color = "#f39c12";
+    } else if (isHooked) {
         indicator.style.background = "#2ecc71";
         indicator.style.boxShadow = "0 0 8px #2ecc71";
         text.innerText = "Connected";
@@ -1123,6 +1435,7 @@ toggleUnlock = function(id) {
     if (appState.unlocks.has(id)) appState.unlocks.delete(id);
     else appState.unlocks.add(id);
     renderUnlocks();
+    scheduleStateSync();
 };
 
 function calculateBranchStatsForZone(zoneID) {
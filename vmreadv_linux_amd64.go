@@ -0,0 +1,4 @@
+package main
+
+// sysProcessVMReadv is the number of the process_vm_readv syscall, which the syscall package lacks on amd64.
+const sysProcessVMReadv = 310
@@ -0,0 +1,8 @@
+//go:build linux && !amd64 && !386
+
+package main
+
+import "syscall"
+
+// sysProcessVMReadv is the number of the process_vm_readv syscall on this architecture.
+const sysProcessVMReadv = syscall.SYS_PROCESS_VM_READV